@@ -1,13 +1,26 @@
 package clients
 
-import "github.com/dkhalizov/shows/internal/models"
+import (
+	"context"
+	"errors"
 
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+// ErrNotFound is returned when a provider has no show for a lookup.
+var ErrNotFound = errors.New("show not found")
+
+// ShowAPIClient is implemented by every show metadata provider.
+// All methods honour ctx cancellation, including retry backoff.
 type ShowAPIClient interface {
-	SearchShows(query string) ([]models.Show, error)
+	SearchShows(ctx context.Context, query string) ([]models.Show, error)
+
+	GetShowDetails(ctx context.Context, id string) (*models.Show, error)
 
-	GetShowDetails(id string) (*models.Show, error)
+	// FindByIMDbID returns the provider's show for an IMDb ID, or ErrNotFound.
+	FindByIMDbID(ctx context.Context, imdbID string) (*models.Show, error)
 
-	GetEpisodes(showID string) ([]models.Episode, error)
+	GetEpisodes(ctx context.Context, showID string) ([]models.Episode, error)
 
-	GetUpcomingEpisodes(showID string) ([]models.Episode, error)
+	GetUpcomingEpisodes(ctx context.Context, showID string) ([]models.Episode, error)
 }
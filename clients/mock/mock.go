@@ -0,0 +1,259 @@
+// Package mock serves show data from JSON fixtures, so the bot runs without provider APIs or keys.
+//
+// Each <provider>.json file in a fixtures directory holds the shows of one provider. Episode dates
+// are either absolute or given in days relative to the client's clock, which keeps "upcoming"
+// episodes upcoming and makes the data reproducible when the clock is pinned.
+package mock
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/dkhalizov/shows/clients"
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+//go:embed fixtures/*.json
+var embedded embed.FS
+
+const dateLayout = "2006-01-02"
+
+type fixtureShow struct {
+	ID           string           `json:"id"`
+	Name         string           `json:"name"`
+	Overview     string           `json:"overview"`
+	PosterURL    string           `json:"poster_url"`
+	Status       string           `json:"status"`
+	FirstAirDate string           `json:"first_air_date"`
+	IMDbID       string           `json:"imdb_id"`
+	Episodes     []fixtureEpisode `json:"episodes"`
+}
+
+type fixtureEpisode struct {
+	ID       string `json:"id"`
+	Season   int    `json:"season"`
+	Number   int    `json:"number"`
+	Name     string `json:"name"`
+	Overview string `json:"overview"`
+	AirDate  string `json:"air_date"`    // absolute date, YYYY-MM-DD
+	AirIn    *int   `json:"air_in_days"` // days after the clock's current UTC date; used when air_date is empty
+	AirTime  string `json:"air_time"`    // optional UTC clock time, HH:MM
+}
+
+// Client implements clients.ShowAPIClient over one provider's fixtures.
+type Client struct {
+	provider string
+	shows    []fixtureShow
+	clock    func() time.Time
+}
+
+// Fixtures returns the fixtures in dir, or the built-in set when dir is empty.
+func Fixtures(dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+
+	sub, _ := fs.Sub(embedded, "fixtures")
+
+	return sub
+}
+
+// Providers lists the providers that have a fixture file in fsys.
+func Providers(fsys fs.FS) ([]string, error) {
+	names, err := fs.Glob(fsys, "*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	providers := make([]string, len(names))
+	for i, name := range names {
+		providers[i] = strings.TrimSuffix(name, path.Ext(name))
+	}
+
+	return providers, nil
+}
+
+// NewClient loads provider's fixtures from fsys. A nil clock means time.Now.
+func NewClient(fsys fs.FS, provider string, clock func() time.Time) (*Client, error) {
+	data, err := fs.ReadFile(fsys, provider+".json")
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s fixtures: %w", provider, err)
+	}
+
+	var shows []fixtureShow
+	if err = json.Unmarshal(data, &shows); err != nil {
+		return nil, fmt.Errorf("could not parse %s fixtures: %w", provider, err)
+	}
+
+	if clock == nil {
+		clock = time.Now
+	}
+
+	return &Client{provider: provider, shows: shows, clock: clock}, nil
+}
+
+// FixedClock returns a clock that always reads t.
+func FixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func (c *Client) SearchShows(ctx context.Context, query string) ([]models.Show, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var shows []models.Show
+
+	for _, fixture := range c.shows {
+		if strings.Contains(strings.ToLower(fixture.Name), query) {
+			shows = append(shows, c.show(fixture))
+		}
+	}
+
+	return shows, nil
+}
+
+func (c *Client) GetShowDetails(ctx context.Context, id string) (*models.Show, error) {
+	fixture, err := c.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	show := c.show(*fixture)
+
+	return &show, nil
+}
+
+func (c *Client) FindByIMDbID(ctx context.Context, imdbID string) (*models.Show, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, fixture := range c.shows {
+		if fixture.IMDbID != "" && fixture.IMDbID == imdbID {
+			show := c.show(fixture)
+
+			return &show, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no %s fixture for IMDb ID %s", clients.ErrNotFound, c.provider, imdbID)
+}
+
+func (c *Client) GetEpisodes(ctx context.Context, showID string) ([]models.Episode, error) {
+	fixture, err := c.find(ctx, showID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := c.clock().UTC().Truncate(24 * time.Hour)
+	episodes := make([]models.Episode, 0, len(fixture.Episodes))
+
+	for _, item := range fixture.Episodes {
+		episode, err := c.episode(item, today)
+		if err != nil {
+			return nil, fmt.Errorf("show %s: %w", showID, err)
+		}
+
+		episodes = append(episodes, episode)
+	}
+
+	return episodes, nil
+}
+
+func (c *Client) GetUpcomingEpisodes(ctx context.Context, showID string) ([]models.Episode, error) {
+	allEpisodes, err := c.GetEpisodes(ctx, showID)
+	if err != nil {
+		return nil, err
+	}
+
+	var upcomingEpisodes []models.Episode
+
+	now := c.clock()
+
+	for _, episode := range allEpisodes {
+		if episode.AirTime().After(now) {
+			upcomingEpisodes = append(upcomingEpisodes, episode)
+		}
+	}
+
+	return upcomingEpisodes, nil
+}
+
+func (c *Client) find(ctx context.Context, id string) (*fixtureShow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range c.shows {
+		if c.shows[i].ID == id {
+			return &c.shows[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no %s fixture for show %s", clients.ErrNotFound, c.provider, id)
+}
+
+func (c *Client) show(fixture fixtureShow) models.Show {
+	show := models.Show{
+		Name:       fixture.Name,
+		Overview:   fixture.Overview,
+		PosterURL:  fixture.PosterURL,
+		Status:     fixture.Status,
+		Provider:   c.provider,
+		ProviderID: fixture.ID,
+		IMDbID:     fixture.IMDbID,
+	}
+
+	if date, err := time.Parse(dateLayout, fixture.FirstAirDate); err == nil {
+		show.FirstAirDate = date
+	}
+
+	return show
+}
+
+func (c *Client) episode(item fixtureEpisode, today time.Time) (models.Episode, error) {
+	episode := models.Episode{
+		Name:          item.Name,
+		Overview:      item.Overview,
+		SeasonNumber:  item.Season,
+		EpisodeNumber: item.Number,
+		Provider:      c.provider,
+		ProviderID:    item.ID,
+	}
+
+	switch {
+	case item.AirDate != "":
+		date, err := time.Parse(dateLayout, item.AirDate)
+		if err != nil {
+			return episode, fmt.Errorf("episode %s: invalid air_date: %w", item.ID, err)
+		}
+
+		episode.AirDate = date
+	case item.AirIn != nil:
+		episode.AirDate = today.AddDate(0, 0, *item.AirIn)
+	default:
+		return episode, nil
+	}
+
+	if item.AirTime != "" {
+		clock, err := time.Parse("15:04", item.AirTime)
+		if err != nil {
+			return episode, fmt.Errorf("episode %s: invalid air_time: %w", item.ID, err)
+		}
+
+		stamp := episode.AirDate.Add(time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute)
+		episode.AirStamp = &stamp
+	}
+
+	return episode, nil
+}
@@ -0,0 +1,86 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket that allows up to requests calls per window,
+// refilling continuously. A nil *RateLimiter never blocks.
+type RateLimiter struct {
+	mu       sync.Mutex
+	tokens   float64
+	capacity float64
+	perSec   float64
+	last     time.Time
+}
+
+// NewRateLimiter returns a limiter allowing requests calls per window,
+// or nil (unlimited) when either value is not positive.
+func NewRateLimiter(requests int, window time.Duration) *RateLimiter {
+	if requests <= 0 || window <= 0 {
+		return nil
+	}
+
+	return &RateLimiter{
+		tokens:   float64(requests),
+		capacity: float64(requests),
+		perSec:   float64(requests) / window.Seconds(),
+		last:     time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return ctx.Err()
+	}
+
+	for {
+		delay := l.Reserve()
+		if delay == 0 {
+			return nil
+		}
+
+		if err := Sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// Reserve takes a token if one is available and returns zero,
+// otherwise it returns how long until the next token is due without taking one.
+func (l *RateLimiter) Reserve() time.Duration {
+	if l == nil {
+		return 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.capacity, l.tokens+now.Sub(l.last).Seconds()*l.perSec)
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+
+		return 0
+	}
+
+	return time.Duration((1 - l.tokens) / l.perSec * float64(time.Second))
+}
+
+// Sleep pauses for d or until ctx is done, whichever comes first.
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
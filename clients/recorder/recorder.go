@@ -0,0 +1,157 @@
+// Package recorder captures provider HTTP responses to disk and replays them, so tests and
+// offline development see real TMDB and TVMaze payloads without calling the APIs.
+package recorder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// secretParams are query parameters left out of recordings and request keys.
+var secretParams = []string{"api_key"}
+
+// ErrNotRecorded is returned in replay mode for a request with no saved response.
+var ErrNotRecorded = errors.New("no recorded response")
+
+type recording struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+	Body   string      `json:"body"`
+}
+
+// Transport records or replays responses under dir, one JSON file per request.
+type Transport struct {
+	dir  string
+	next http.RoundTripper // nil when replaying
+}
+
+// NewRecorder passes requests to next, or http.DefaultTransport when nil, and saves each response under dir.
+func NewRecorder(dir string, next http.RoundTripper) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{dir: dir, next: next}
+}
+
+// NewReplayer answers requests only from responses saved under dir.
+func NewReplayer(dir string) *Transport {
+	return &Transport{dir: dir}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	file := t.path(req)
+
+	if t.next == nil {
+		return t.replay(req, file)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Throttling and server errors are transient; replaying them would only fail tests.
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := recording{
+		Method: req.Method,
+		URL:    redact(req.URL).String(),
+		Status: resp.StatusCode,
+		Header: http.Header{"Content-Type": resp.Header.Values("Content-Type")},
+		Body:   string(body),
+	}
+
+	if err = save(file, rec); err != nil {
+		return nil, fmt.Errorf("could not save recording: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (t *Transport) replay(req *http.Request, file string) (*http.Response, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w for %s %s", ErrNotRecorded, req.Method, redact(req.URL))
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rec recording
+	if err = json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("could not parse recording %s: %w", file, err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        rec.Header,
+		Body:          io.NopCloser(strings.NewReader(rec.Body)),
+		ContentLength: int64(len(rec.Body)),
+		Request:       req,
+	}, nil
+}
+
+// path names the recording of req: <dir>/<hostname>/<method>_<hash of the redacted URL>.json.
+func (t *Transport) path(req *http.Request) string {
+	u := redact(req.URL)
+	sum := sha256.Sum256([]byte(req.Method + " " + u.String()))
+
+	return filepath.Join(t.dir, u.Hostname(), fmt.Sprintf("%s_%s.json", strings.ToLower(req.Method), hex.EncodeToString(sum[:8])))
+}
+
+// redact drops secret parameters and sorts the query so equal requests share a recording.
+func redact(u *url.URL) *url.URL {
+	clean := *u
+	query := u.Query()
+
+	for _, param := range secretParams {
+		query.Del(param)
+	}
+
+	// Encode sorts by key.
+	clean.RawQuery = query.Encode()
+
+	return &clean
+}
+
+func save(file string, rec recording) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, append(data, '\n'), 0o644)
+}
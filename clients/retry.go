@@ -0,0 +1,97 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxRetryAfter = time.Minute
+
+// GetWithRetry performs a rate-limited GET request, retrying transport errors,
+// 5xx responses and 429 Too Many Requests up to maxRetries times.
+// 429 responses honour the Retry-After header; everything else backs off exponentially.
+func GetWithRetry(
+	ctx context.Context,
+	client *http.Client,
+	limiter *RateLimiter,
+	maxRetries int,
+	url string,
+) (*http.Response, error) {
+	var err error
+
+	for i := 0; i <= maxRetries; i++ {
+		if err = limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		var req *http.Request
+
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var resp *http.Response
+
+		resp, err = client.Do(req)
+		if err == nil && !shouldRetry(resp.StatusCode) {
+			return resp, nil
+		}
+
+		delay := retryDelay(resp, i)
+
+		if resp != nil {
+			err = fmt.Errorf("API returned status %d", resp.StatusCode)
+			resp.Body.Close()
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		if i < maxRetries {
+			if err := Sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("failed after %d attempts: %w", maxRetries+1, err)
+}
+
+func shouldRetry(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// retryDelay returns the server-requested Retry-After delay for 429 responses,
+// falling back to exponential backoff.
+func retryDelay(resp *http.Response, attempt int) time.Duration {
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return min(d, maxRetryAfter)
+		}
+	}
+
+	// nolint:gosec
+	return time.Duration(1<<uint(attempt)) * time.Second
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 9110: delay-seconds and an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+
+	return 0, false
+}
@@ -1,6 +1,7 @@
 package tmdb
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,6 +9,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/dkhalizov/shows/clients"
 	"github.com/dkhalizov/shows/internal/models"
 )
 
@@ -17,6 +19,7 @@ type Client struct {
 	httpClient  *http.Client
 	usePosterV2 bool
 	maxRetries  int
+	limiter     *clients.RateLimiter
 }
 
 func NewClient(apiKey string) *Client {
@@ -24,6 +27,11 @@ func NewClient(apiKey string) *Client {
 
 	const retries = 3
 
+	const (
+		defaultRateLimit  = 40
+		defaultRateWindow = 10 * time.Second
+	)
+
 	return &Client{
 		apiKey:  apiKey,
 		baseURL: "https://api.themoviedb.org/3",
@@ -32,6 +40,7 @@ func NewClient(apiKey string) *Client {
 		},
 		maxRetries:  retries,
 		usePosterV2: false,
+		limiter:     clients.NewRateLimiter(defaultRateLimit, defaultRateWindow),
 	}
 }
 
@@ -47,17 +56,31 @@ func (c *Client) SetTimeout(timeout time.Duration) {
 	}
 }
 
+// SetTransport routes the client's requests through rt, e.g. to record or replay them.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
 func (c *Client) SetMaxRetries(maxRetries int) {
 	if maxRetries > 0 {
 		c.maxRetries = maxRetries
 	}
 }
 
+// SetRateLimit limits the client to requests calls per window; non-positive values disable limiting.
+func (c *Client) SetRateLimit(requests int, window time.Duration) {
+	c.limiter = clients.NewRateLimiter(requests, window)
+}
+
 func (c *Client) EnablePosterV2(enabled bool) {
 	c.usePosterV2 = enabled
 }
 
 func (c *Client) getPosterURL(posterPath string) string {
+	if posterPath == "" {
+		return ""
+	}
+
 	if c.usePosterV2 {
 		return fmt.Sprintf("https://image.tmdb.org/t/p/w780%s", posterPath)
 	}
@@ -65,36 +88,15 @@ func (c *Client) getPosterURL(posterPath string) string {
 	return fmt.Sprintf("https://image.tmdb.org/t/p/w500%s", posterPath)
 }
 
-func (c *Client) makeRequest(url string) (*http.Response, error) {
-	var resp *http.Response
-
-	var err error
-
-	for i := 0; i <= c.maxRetries; i++ {
-		resp, err = c.httpClient.Get(url)
-		if err == nil && resp.StatusCode < 500 {
-			return resp, nil
-		}
-
-		if resp != nil {
-			resp.Body.Close()
-		}
-
-		if i < c.maxRetries {
-			// nolint:gosec
-			backoff := time.Duration(1<<uint(i)) * time.Second
-			time.Sleep(backoff)
-		}
-	}
-
-	return nil, fmt.Errorf("failed after %d attempts: %w", c.maxRetries+1, err)
+func (c *Client) makeRequest(ctx context.Context, url string) (*http.Response, error) {
+	return clients.GetWithRetry(ctx, c.httpClient, c.limiter, c.maxRetries, url)
 }
 
-func (c *Client) SearchShows(query string) ([]models.Show, error) {
+func (c *Client) SearchShows(ctx context.Context, query string) ([]models.Show, error) {
 	encodedQuery := url.QueryEscape(query)
 	url := fmt.Sprintf("%s/search/tv?api_key=%s&query=%s", c.baseURL, c.apiKey, encodedQuery)
 
-	resp, err := c.makeRequest(url)
+	resp, err := c.makeRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -139,7 +141,7 @@ func (c *Client) SearchShows(query string) ([]models.Show, error) {
 
 	for i, item := range result.Results {
 		if shows[i].IMDbID == "" {
-			details, err := c.GetShowDetails(strconv.Itoa(item.ID))
+			details, err := c.GetShowDetails(ctx, strconv.Itoa(item.ID))
 			if err == nil && details.IMDbID != "" {
 				shows[i].IMDbID = details.IMDbID
 			}
@@ -149,10 +151,10 @@ func (c *Client) SearchShows(query string) ([]models.Show, error) {
 	return shows, nil
 }
 
-func (c *Client) GetShowDetails(id string) (*models.Show, error) {
+func (c *Client) GetShowDetails(ctx context.Context, id string) (*models.Show, error) {
 	url := fmt.Sprintf("%s/tv/%s?append_to_response=external_ids&api_key=%s", c.baseURL, id, c.apiKey)
 
-	resp, err := c.makeRequest(url)
+	resp, err := c.makeRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -178,7 +180,7 @@ func (c *Client) GetShowDetails(id string) (*models.Show, error) {
 	show := &models.Show{
 		Name:       result.Name,
 		Overview:   result.Overview,
-		PosterURL:  fmt.Sprintf("https://image.tmdb.org/t/p/w500%s", result.PosterPath),
+		PosterURL:  c.getPosterURL(result.PosterPath),
 		Status:     result.Status,
 		Provider:   "tmdb",
 		ProviderID: strconv.Itoa(result.ID),
@@ -195,10 +197,41 @@ func (c *Client) GetShowDetails(id string) (*models.Show, error) {
 	return show, nil
 }
 
-func (c *Client) GetEpisodes(showID string) ([]models.Episode, error) {
+// FindByIMDbID resolves the IMDb ID through TMDB's find endpoint and loads the show's details.
+func (c *Client) FindByIMDbID(ctx context.Context, imdbID string) (*models.Show, error) {
+	url := fmt.Sprintf("%s/find/%s?external_source=imdb_id&api_key=%s", c.baseURL, url.PathEscape(imdbID), c.apiKey)
+
+	resp, err := c.makeRequest(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, clients.ErrNotFound
+	}
+
+	var result struct {
+		TVResults []struct {
+			ID int `json:"id"`
+		} `json:"tv_results"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if len(result.TVResults) == 0 {
+		return nil, clients.ErrNotFound
+	}
+
+	return c.GetShowDetails(ctx, strconv.Itoa(result.TVResults[0].ID))
+}
+
+func (c *Client) GetEpisodes(ctx context.Context, showID string) ([]models.Episode, error) {
 	seasonsURL := fmt.Sprintf("%s/tv/%s?api_key=%s", c.baseURL, showID, c.apiKey)
 
-	resp, err := c.makeRequest(seasonsURL)
+	resp, err := c.makeRequest(ctx, seasonsURL)
 	if err != nil {
 		return nil, err
 	}
@@ -227,8 +260,12 @@ func (c *Client) GetEpisodes(showID string) ([]models.Episode, error) {
 		episodesURL := fmt.Sprintf("%s/tv/%s/season/%d?api_key=%s",
 			c.baseURL, showID, season.SeasonNumber, c.apiKey)
 
-		resp, err := c.httpClient.Get(episodesURL)
+		resp, err := c.makeRequest(ctx, episodesURL)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
 			continue
 		}
 
@@ -274,8 +311,8 @@ func (c *Client) GetEpisodes(showID string) ([]models.Episode, error) {
 	return allEpisodes, nil
 }
 
-func (c *Client) GetUpcomingEpisodes(showID string) ([]models.Episode, error) {
-	allEpisodes, err := c.GetEpisodes(showID)
+func (c *Client) GetUpcomingEpisodes(ctx context.Context, showID string) ([]models.Episode, error) {
+	allEpisodes, err := c.GetEpisodes(ctx, showID)
 	if err != nil {
 		return nil, err
 	}
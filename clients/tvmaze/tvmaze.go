@@ -1,6 +1,7 @@
 package tvmaze
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/dkhalizov/shows/clients"
 	"github.com/dkhalizov/shows/internal/models"
 )
 
@@ -16,16 +18,24 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	maxRetries int
+	limiter    *clients.RateLimiter
 }
 
 func NewClient() *Client {
 	const defaultTimeout = 10 * time.Second
 
+	// TVMaze allows at least 20 calls every 10 seconds per IP.
+	const (
+		defaultRateLimit  = 20
+		defaultRateWindow = 10 * time.Second
+	)
+
 	return &Client{
 		baseURL: "https://api.tvmaze.com",
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
+		limiter: clients.NewRateLimiter(defaultRateLimit, defaultRateWindow),
 	}
 }
 
@@ -41,42 +51,31 @@ func (c *Client) SetTimeout(timeout time.Duration) {
 	}
 }
 
+// SetTransport routes the client's requests through rt, e.g. to record or replay them.
+func (c *Client) SetTransport(rt http.RoundTripper) {
+	c.httpClient.Transport = rt
+}
+
 func (c *Client) SetMaxRetries(maxRetries int) {
 	if maxRetries > 0 {
 		c.maxRetries = maxRetries
 	}
 }
 
-func (c *Client) makeRequest(url string) (*http.Response, error) {
-	var resp *http.Response
-
-	var err error
-
-	for i := 0; i <= c.maxRetries; i++ {
-		resp, err = c.httpClient.Get(url)
-		if err == nil && resp.StatusCode < 500 {
-			return resp, nil
-		}
-
-		if resp != nil {
-			resp.Body.Close()
-		}
-
-		if i < c.maxRetries {
-			// nolint:gosec
-			backoff := time.Duration(1<<uint(i)) * time.Second
-			time.Sleep(backoff)
-		}
-	}
+// SetRateLimit limits the client to requests calls per window; non-positive values disable limiting.
+func (c *Client) SetRateLimit(requests int, window time.Duration) {
+	c.limiter = clients.NewRateLimiter(requests, window)
+}
 
-	return nil, fmt.Errorf("failed after %d attempts: %w", c.maxRetries+1, err)
+func (c *Client) makeRequest(ctx context.Context, url string) (*http.Response, error) {
+	return clients.GetWithRetry(ctx, c.httpClient, c.limiter, c.maxRetries, url)
 }
 
-func (c *Client) SearchShows(query string) ([]models.Show, error) {
+func (c *Client) SearchShows(ctx context.Context, query string) ([]models.Show, error) {
 	encodedQuery := url.QueryEscape(query)
 	url := fmt.Sprintf("%s/search/shows?q=%s", c.baseURL, encodedQuery)
 
-	resp, err := c.makeRequest(url)
+	resp, err := c.makeRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -141,15 +140,35 @@ func (c *Client) SearchShows(query string) ([]models.Show, error) {
 	return shows, nil
 }
 
-func (c *Client) GetShowDetails(id string) (*models.Show, error) {
-	url := fmt.Sprintf("%s/shows/%s", c.baseURL, id)
+func (c *Client) GetShowDetails(ctx context.Context, id string) (*models.Show, error) {
+	return c.getShow(ctx, fmt.Sprintf("%s/shows/%s", c.baseURL, id))
+}
 
-	resp, err := c.makeRequest(url)
+// FindByIMDbID uses TVMaze's lookup endpoint, which redirects to the show.
+func (c *Client) FindByIMDbID(ctx context.Context, imdbID string) (*models.Show, error) {
+	show, err := c.getShow(ctx, fmt.Sprintf("%s/lookup/shows?imdb=%s", c.baseURL, url.QueryEscape(imdbID)))
+	if err != nil {
+		return nil, err
+	}
+
+	if show.IMDbID == "" {
+		show.IMDbID = imdbID
+	}
+
+	return show, nil
+}
+
+func (c *Client) getShow(ctx context.Context, url string) (*models.Show, error) {
+	resp, err := c.makeRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, clients.ErrNotFound
+	}
+
 	var result struct {
 		ID      int    `json:"id"`
 		Name    string `json:"name"`
@@ -159,6 +178,9 @@ func (c *Client) GetShowDetails(id string) (*models.Show, error) {
 		} `json:"image"`
 		Premiered string `json:"premiered"`
 		Status    string `json:"status"`
+		Externals struct {
+			IMDb string `json:"imdb"`
+		} `json:"externals"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -171,6 +193,7 @@ func (c *Client) GetShowDetails(id string) (*models.Show, error) {
 		Status:     result.Status,
 		Provider:   "tvmaze",
 		ProviderID: strconv.Itoa(result.ID),
+		IMDbID:     result.Externals.IMDb,
 	}
 
 	if result.Image.Medium != "" {
@@ -187,22 +210,23 @@ func (c *Client) GetShowDetails(id string) (*models.Show, error) {
 	return show, nil
 }
 
-func (c *Client) GetEpisodes(showID string) ([]models.Episode, error) {
+func (c *Client) GetEpisodes(ctx context.Context, showID string) ([]models.Episode, error) {
 	url := fmt.Sprintf("%s/shows/%s/episodes", c.baseURL, showID)
 
-	resp, err := c.makeRequest(url)
+	resp, err := c.makeRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var result []struct {
-		ID      int    `json:"id"`
-		Name    string `json:"name"`
-		Season  int    `json:"season"`
-		Number  int    `json:"number"`
-		Airdate string `json:"airdate"`
-		Summary string `json:"summary"`
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Season   int    `json:"season"`
+		Number   int    `json:"number"`
+		Airdate  string `json:"airdate"`
+		Airstamp string `json:"airstamp"`
+		Summary  string `json:"summary"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -228,14 +252,22 @@ func (c *Client) GetEpisodes(showID string) ([]models.Episode, error) {
 			}
 		}
 
+		if item.Airstamp != "" {
+			stamp, err := time.Parse(time.RFC3339, item.Airstamp)
+			if err == nil {
+				stamp = stamp.UTC()
+				episode.AirStamp = &stamp
+			}
+		}
+
 		episodes[i] = episode
 	}
 
 	return episodes, nil
 }
 
-func (c *Client) GetUpcomingEpisodes(showID string) ([]models.Episode, error) {
-	allEpisodes, err := c.GetEpisodes(showID)
+func (c *Client) GetUpcomingEpisodes(ctx context.Context, showID string) ([]models.Episode, error) {
+	allEpisodes, err := c.GetEpisodes(ctx, showID)
 	if err != nil {
 		return nil, err
 	}
@@ -245,7 +277,7 @@ func (c *Client) GetUpcomingEpisodes(showID string) ([]models.Episode, error) {
 	now := time.Now()
 
 	for _, episode := range allEpisodes {
-		if episode.AirDate.After(now) {
+		if episode.AirTime().After(now) {
 			upcomingEpisodes = append(upcomingEpisodes, episode)
 		}
 	}
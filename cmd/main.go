@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
 	"log"
 	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata" // user time zones must resolve even on images without zoneinfo
 
 	"github.com/dkhalizov/shows/internal/bot"
 	"github.com/dkhalizov/shows/internal/config"
@@ -17,12 +22,25 @@ func main() {
 
 	slog.Debug("Loaded", "config", cfg)
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(cfg, os.Args[2:]); err != nil {
+			log.Fatal("Migration failed: ", err)
+		}
+
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	b, err := bot.New(cfg)
 	if err != nil {
 		log.Fatal("Failed to create bot:", err)
 	}
 
-	if err = b.Start(); err != nil {
+	if err = b.Start(ctx); err != nil {
 		log.Fatal("Failed to start bot:", err)
 	}
+
+	slog.Info("Bot stopped")
 }
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/dkhalizov/shows/internal/config"
+	"github.com/dkhalizov/shows/internal/database"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status"
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	manager, err := database.NewManager(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer manager.Close()
+
+	switch args[0] {
+	case "up":
+		applied, err := manager.MigrateUp()
+		fmt.Printf("Applied %d migration(s)\n", applied)
+
+		return err
+	case "down":
+		steps := 1
+
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+
+		reverted, err := manager.MigrateDown(steps)
+		fmt.Printf("Reverted %d migration(s)\n", reverted)
+
+		return err
+	case "status":
+		statuses, err := manager.MigrationStatus()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+
+			switch {
+			case status.Dirty:
+				state = "dirty"
+			case status.Applied:
+				state = "applied"
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+
+		return w.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+}
@@ -1,14 +1,16 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"sync"
 	"time"
 
-	"github.com/dkhalizov/shows/internal/models"
-
 	"github.com/dkhalizov/shows/clients"
+	"github.com/dkhalizov/shows/clients/mock"
+	"github.com/dkhalizov/shows/clients/recorder"
 	"github.com/dkhalizov/shows/clients/tmdb"
 	"github.com/dkhalizov/shows/clients/tvmaze"
 	"github.com/dkhalizov/shows/internal/config"
@@ -17,95 +19,360 @@ import (
 )
 
 type Bot struct {
-	api           *tgbotapi.BotAPI
+	api           Messenger
+	sender        *sender
 	apiClients    map[string]clients.ShowAPIClient
 	notifyTicker  *time.Ticker
 	checkInterval time.Duration
 	dbManager     Operations
 	config        config.Config
+	searches      *searchSessions
+	searchCache   *searchCache
+
+	// inFlight tracks update handlers and background jobs that must finish before shutdown.
+	inFlight sync.WaitGroup
 }
 
-func New(config config.Config) (*Bot, error) {
-	cli := makeHttpClient(config)
+const (
+	defaultShutdownTimeout = 30 * time.Second
+	defaultUpdateTimeout   = 30 * time.Second
+	defaultRefreshTimeout  = 2 * time.Minute
+
+	// cancelGracePeriod is how long cancelled work gets to return before the database is closed.
+	cancelGracePeriod = 5 * time.Second
+
+	defaultShowRefreshInterval = 24 * time.Hour
+	defaultReminderInterval    = 5 * time.Minute
+
+	// rescheduleAlertWindow limits reschedule alerts to episodes airing soon.
+	rescheduleAlertWindow = 30 * 24 * time.Hour
+
+	// rateLimitWindow is the period the api_clients.*.rate_limit settings are expressed in.
+	rateLimitWindow = 10 * time.Second
 
-	bot, err := tgbotapi.NewBotAPIWithClient(config.TelegramToken, cli)
+	pollTimeout    = 60 // seconds a getUpdates call waits for new updates
+	pollRetryDelay = 3 * time.Second
+)
+
+// Deps are the collaborators New builds from the config; tests pass fakes to NewWithDeps.
+type Deps struct {
+	Messenger  Messenger
+	Operations Operations
+	APIClients map[string]clients.ShowAPIClient
+}
+
+func New(config config.Config) (*Bot, error) {
+	messenger, err := NewMessenger(config.TelegramToken, makeHttpClient(config), config.Development.DebugMode)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize Telegram API: %w", err)
 	}
 
-	bot.Debug = config.Development.DebugMode
-
 	dbManager, err := database.NewManager(config.Database)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database manager: %w", err)
 	}
 
-	apiClients := makeAPIClients(config)
+	apiClients, err := makeAPIClients(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize show providers: %w", err)
+	}
+
+	return NewWithDeps(config, Deps{
+		Messenger:  messenger,
+		Operations: dbManager,
+		APIClients: apiClients,
+	}), nil
+}
 
+// NewWithDeps returns a bot using deps instead of connecting to Telegram, the database and providers.
+func NewWithDeps(config config.Config, deps Deps) *Bot {
 	return &Bot{
-		api:           bot,
-		dbManager:     dbManager,
-		apiClients:    apiClients,
+		api:           deps.Messenger,
+		sender:        newSender(deps.Messenger.Send, config.Bot.SendRateLimit, config.Bot.ChatSendRateLimit),
+		dbManager:     deps.Operations,
+		apiClients:    deps.APIClients,
 		notifyTicker:  time.NewTicker(config.Bot.CheckInterval),
 		checkInterval: config.Bot.CheckInterval,
 		config:        config,
-	}, nil
+		searches:      newSearchSessions(),
+		searchCache:   newSearchCache(),
+	}
 }
 
-func makeAPIClients(config config.Config) map[string]clients.ShowAPIClient {
+func makeAPIClients(config config.Config) (map[string]clients.ShowAPIClient, error) {
+	if config.Development.MockAPIs {
+		return makeMockClients(config.Development)
+	}
+
 	apiClients := make(map[string]clients.ShowAPIClient)
+	transport := providerTransport(config.Development)
 
 	if tmdbKey, ok := config.APIKeys["tmdb"]; ok {
 		tmdbClient := tmdb.NewClient(tmdbKey)
 
+		if transport != nil {
+			tmdbClient.SetTransport(transport)
+		}
+
 		tmdbClient.SetBaseURL(config.APIClients.TMDB.BaseURL)
 		tmdbClient.SetTimeout(config.APIClients.TMDB.Timeout)
+		tmdbClient.SetMaxRetries(config.APIClients.TMDB.MaxRetries)
+
+		if config.APIClients.TMDB.RateLimit > 0 {
+			tmdbClient.SetRateLimit(config.APIClients.TMDB.RateLimit, rateLimitWindow)
+		}
 
 		apiClients["tmdb"] = tmdbClient
 	}
 
 	tvmazeClient := tvmaze.NewClient()
+
+	if transport != nil {
+		tvmazeClient.SetTransport(transport)
+	}
+
 	tvmazeClient.SetBaseURL(config.APIClients.TVMaze.BaseURL)
 	tvmazeClient.SetTimeout(config.APIClients.TVMaze.Timeout)
+	tvmazeClient.SetMaxRetries(config.APIClients.TVMaze.MaxRetries)
+
+	if config.APIClients.TVMaze.RateLimit > 0 {
+		tvmazeClient.SetRateLimit(config.APIClients.TVMaze.RateLimit, rateLimitWindow)
+	}
 
 	apiClients["tvmaze"] = tvmazeClient
 
-	return apiClients
+	return apiClients, nil
+}
+
+// makeMockClients serves every provider with a fixture file from the configured fixtures.
+func makeMockClients(dev config.Development) (map[string]clients.ShowAPIClient, error) {
+	fixtures := mock.Fixtures(dev.FixturesDir)
+
+	providers, err := mock.Providers(fixtures)
+	if err != nil {
+		return nil, err
+	}
+
+	var clock func() time.Time
+	if !dev.MockNow.IsZero() {
+		clock = mock.FixedClock(dev.MockNow)
+	}
+
+	apiClients := make(map[string]clients.ShowAPIClient, len(providers))
+
+	for _, provider := range providers {
+		client, err := mock.NewClient(fixtures, provider, clock)
+		if err != nil {
+			return nil, err
+		}
+
+		apiClients[provider] = client
+	}
+
+	slog.Info("Using mock show providers", "providers", providers, "fixtures", dev.FixturesDir)
+
+	return apiClients, nil
+}
+
+// providerTransport returns the recording or replaying transport configured for provider
+// requests, or nil to use the default.
+func providerTransport(dev config.Development) http.RoundTripper {
+	switch dev.HTTPRecording {
+	case config.RecordingRecord:
+		slog.Info("Recording provider responses", "dir", dev.RecordingsDir)
+
+		return recorder.NewRecorder(dev.RecordingsDir, nil)
+	case config.RecordingReplay:
+		slog.Info("Replaying recorded provider responses", "dir", dev.RecordingsDir)
+
+		return recorder.NewReplayer(dev.RecordingsDir)
+	default:
+		return nil
+	}
 }
 
-func (b *Bot) Start() error {
+// Start receives updates until ctx is cancelled, then stops accepting new updates,
+// drains in-flight work within Server.ShutdownTimeout and closes the database.
+func (b *Bot) Start(ctx context.Context) error {
 	slog.Info("Starting Telegram Bot")
 
 	if err := b.dbManager.Init(); err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	// Handlers outlive ctx so they can finish replying during the drain;
+	// they are cancelled only once the shutdown timeout expires.
+	handlerCtx, cancelHandlers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelHandlers()
+
+	// The sender serves handlers and the outbox during the drain, so it stops with them.
+	go b.sender.run(handlerCtx)
+
+	b.goTracked(func() { b.runOutboxDispatcher(ctx) })
+
 	if b.config.Bot.NotificationEnabled {
 		slog.Debug("Starting notification checker...")
 
-		go b.runNotificationChecker()
+		b.goTracked(func() { b.runNotificationChecker(ctx, handlerCtx) })
 	} else {
 		slog.Debug("Notifications are disabled in config")
 	}
 
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
+	// In webhook mode the HTTP server is started with the webhook route.
+	if b.config.Calendar.Enabled && b.config.Bot.Mode != config.ModeWebhook {
+		b.goTracked(func() {
+			if err := b.serveHTTP(ctx, b.newServeMux()); err != nil {
+				slog.Error("HTTP server stopped", "err", err)
+			}
+		})
+	}
 
-	updates, err := b.api.GetUpdatesChan(u)
-	if err != nil {
-		return fmt.Errorf("failed to get updates channel: %w", err)
+	var err error
+	if b.config.Bot.Mode == config.ModeWebhook {
+		err = b.startWebhook(ctx, handlerCtx)
+	} else {
+		err = b.startPolling(ctx, handlerCtx)
 	}
 
-	for update := range updates {
-		go b.processUpdate(update)
+	stop()
+	b.shutdown(cancelHandlers)
+
+	return err
+}
+
+func (b *Bot) startPolling(ctx, handlerCtx context.Context) error {
+	updates := make(chan tgbotapi.Update)
+
+	go b.pollUpdates(ctx, updates)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Stopping update polling")
+
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				return nil
+			}
+
+			b.goTracked(func() { b.processUpdate(handlerCtx, update) })
+		}
+	}
+}
+
+// pollUpdates long-polls getUpdates into updates until ctx is cancelled, then closes it.
+func (b *Bot) pollUpdates(ctx context.Context, updates chan<- tgbotapi.Update) {
+	defer close(updates)
+
+	config := tgbotapi.NewUpdate(0)
+	config.Timeout = pollTimeout
+
+	for ctx.Err() == nil {
+		batch, err := b.api.GetUpdates(config)
+		if err != nil {
+			slog.Error("Failed to get updates, retrying", "err", err, "retryIn", pollRetryDelay)
+
+			if clients.Sleep(ctx, pollRetryDelay) != nil {
+				return
+			}
+
+			continue
+		}
+
+		for _, update := range batch {
+			config.Offset = max(config.Offset, update.UpdateID+1)
+
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// goTracked runs fn in a goroutine that shutdown waits for.
+func (b *Bot) goTracked(fn func()) {
+	b.inFlight.Add(1)
+
+	go func() {
+		defer b.inFlight.Done()
+
+		fn()
+	}()
+}
+
+func (b *Bot) shutdownTimeout() time.Duration {
+	if b.config.Server.ShutdownTimeout > 0 {
+		return b.config.Server.ShutdownTimeout
 	}
 
-	return nil
+	return defaultShutdownTimeout
 }
 
-func (b *Bot) processUpdate(update tgbotapi.Update) {
+func (b *Bot) shutdown(cancelHandlers context.CancelFunc) {
+	timeout := b.shutdownTimeout()
+
+	slog.Info("Waiting for in-flight work to finish", "timeout", timeout)
+
+	drained := make(chan struct{})
+
+	go func() {
+		b.inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		slog.Info("In-flight work finished")
+	case <-time.After(timeout):
+		slog.Warn("Shutdown timeout exceeded, cancelling remaining work")
+		cancelHandlers()
+
+		// Cancelled handlers still touch the database on their way out.
+		select {
+		case <-drained:
+		case <-time.After(cancelGracePeriod):
+			slog.Warn("Cancelled work did not finish, closing the database anyway")
+		}
+	}
+
+	if err := b.dbManager.Close(); err != nil {
+		slog.Error("Failed to close database", "err", err)
+	}
+}
+
+// withTimeout bounds ctx by timeout, falling back to def when timeout is not configured.
+func withTimeout(ctx context.Context, timeout, def time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = def
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+func (b *Bot) processUpdate(ctx context.Context, update tgbotapi.Update) {
+	ctx, cancel := withTimeout(ctx, b.config.Bot.UpdateTimeout, defaultUpdateTimeout)
+	defer cancel()
+
 	if update.CallbackQuery != nil {
-		b.handleCallbackQuery(update.CallbackQuery)
+		b.handleCallbackQuery(ctx, update.CallbackQuery)
+
+		return
+	}
+
+	if update.InlineQuery != nil {
+		b.handleInlineQuery(ctx, update.InlineQuery)
+
+		return
+	}
+
+	if update.ChosenInlineResult != nil {
+		b.handleChosenInlineResult(update.ChosenInlineResult)
 
 		return
 	}
@@ -114,43 +381,60 @@ func (b *Bot) processUpdate(update tgbotapi.Update) {
 		return
 	}
 
-	if update.Message.Text == "" {
+	if update.Message.Text == "" && update.Message.Document == nil {
 		return
 	}
 
-	if err := b.dbManager.StoreUser(models.FromTelegramUser(update.Message.From)); err != nil {
+	// Groups only talk to the bot through commands; other messages there are for people.
+	if !update.Message.Chat.IsPrivate() && !update.Message.IsCommand() {
+		return
+	}
+
+	if err := b.dbManager.StoreUser(subscriber(update.Message)); err != nil {
 		slog.Error("failed storing user", "err", err)
 	}
 
+	if update.Message.Document != nil {
+		b.handleDocument(ctx, update.Message)
+
+		return
+	}
+
 	if update.Message.IsCommand() {
-		b.handleCommand(update.Message)
+		b.handleCommand(ctx, update.Message)
 
 		return
 	}
 
-	b.handleTextMessage(update.Message)
+	b.handleTextMessage(ctx, update.Message)
 }
 
-func (b *Bot) sendMessage(chatID int64, text string) {
-	text = escapeMarkdown(text)
-	msg := tgbotapi.NewMessage(chatID, text)
+// newMessage formats text the way every bot message is sent.
+func (b *Bot) newMessage(chatID int64, text string) tgbotapi.MessageConfig {
+	msg := tgbotapi.NewMessage(chatID, escapeMarkdown(truncateForTelegram(text)))
 	msg.ParseMode = "MarkdownV2"
 
-	_, err := b.api.Send(msg)
+	return msg
+}
+
+func (b *Bot) sendMessage(chatID int64, text string) {
+	msg := b.newMessage(chatID, text)
+
+	_, err := b.sender.sendNow(chatID, msg, laneInteractive)
 	if err != nil {
 		slog.Error("Error sending message", "err", err)
+		b.deactivateIfUnreachable(chatID, err)
 	}
 }
 
 func (b *Bot) sendMessageWithMarkup(chatID int64, text string, ikm tgbotapi.InlineKeyboardMarkup) {
-	text = escapeMarkdown(text)
-	msg := tgbotapi.NewMessage(chatID, text)
+	msg := b.newMessage(chatID, text)
 	msg.ReplyMarkup = ikm
-	msg.ParseMode = "MarkdownV2"
 
-	_, err := b.api.Send(msg)
+	_, err := b.sender.sendNow(chatID, msg, laneInteractive)
 	if err != nil {
 		slog.Error("Error sending message", "err", err)
+		b.deactivateIfUnreachable(chatID, err)
 	}
 }
 
@@ -0,0 +1,262 @@
+package bot_test
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dkhalizov/shows/clients"
+	"github.com/dkhalizov/shows/clients/mock"
+	"github.com/dkhalizov/shows/internal/bot"
+	"github.com/dkhalizov/shows/internal/bot/telegramtest"
+	"github.com/dkhalizov/shows/internal/config"
+	"github.com/dkhalizov/shows/internal/database/memory"
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+// glassKingdom is the stored ID of the tmdb fixture whose next episode, "Cracks", airs in three days.
+const glassKingdom = "tmdb_2002"
+
+// startBot runs a polling bot on store and the fixture providers against a fake Telegram server
+// until the test ends.
+func startBot(t *testing.T, store *memory.Store) *telegramtest.Server {
+	t.Helper()
+
+	server := telegramtest.NewServer(t)
+
+	apiClients := make(map[string]clients.ShowAPIClient)
+
+	for _, provider := range []string{"tmdb", "tvmaze"} {
+		client, err := mock.NewClient(mock.Fixtures(""), provider, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		apiClients[provider] = client
+	}
+
+	b := bot.NewWithDeps(config.DefaultConfig(), bot.Deps{
+		Messenger:  server.Messenger(t),
+		Operations: store,
+		APIClients: apiClients,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() { done <- b.Start(ctx) }()
+
+	t.Cleanup(func() {
+		cancel()
+
+		if err := <-done; err != nil {
+			t.Errorf("Start() = %v", err)
+		}
+	})
+
+	return server
+}
+
+// seedFollower stores user userID following Glass Kingdom.
+func seedFollower(t *testing.T, store *memory.Store, userID int64) {
+	t.Helper()
+
+	must(t, store.StoreUser(models.User{ID: userID, Username: "user", Active: true}))
+
+	if _, err := store.StoreShow(&models.Show{Provider: "tmdb", ProviderID: "2002", Name: "Glass Kingdom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	must(t, store.FollowShow(int(userID), glassKingdom))
+}
+
+// waitCall waits for a call to method that satisfies match.
+func waitCall(t *testing.T, server *telegramtest.Server, method string, match func(telegramtest.Call) bool) telegramtest.Call {
+	t.Helper()
+
+	deadline := time.Now().Add(telegramtest.WaitTimeout)
+
+	for time.Now().Before(deadline) {
+		for _, call := range server.Calls(method) {
+			if match(call) {
+				return call
+			}
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("no matching %s call after %s; got %+v", method, telegramtest.WaitTimeout, server.Calls(method))
+
+	return telegramtest.Call{}
+}
+
+// sentTo matches calls to chatID whose text contains text.
+func sentTo(chatID int64, text string) func(telegramtest.Call) bool {
+	return func(call telegramtest.Call) bool {
+		return call.ChatID() == chatID && strings.Contains(call.Text(), text)
+	}
+}
+
+func hasButton(call telegramtest.Call, data string) bool {
+	for _, row := range call.Buttons() {
+		if slices.Contains(row, data) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMessages(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		want       string
+		wantButton string
+	}{
+		{"start", "/start", "Welcome to the TV Shows Notification Bot", bot.MenuSearch},
+		{"search without query", "/search", "Please provide a show name to search for", ""},
+		{"search", "/search glass", `Search Results for "glass"`, "follow:" + glassKingdom},
+		{"search merges providers", "harbor", "Found 1 shows", ""},
+		{"search without results", "/search zzz", "No shows found for query: zzz", ""},
+		{"unknown command", "/nope", "Unknown command", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := startBot(t, memory.New())
+			server.SendText(telegramtest.User(1), tt.text)
+
+			call := waitCall(t, server, "sendMessage", sentTo(1, tt.want))
+			if tt.wantButton != "" && !hasButton(call, tt.wantButton) {
+				t.Errorf("buttons = %v, want %s", call.Buttons(), tt.wantButton)
+			}
+		})
+	}
+}
+
+func TestFollowCallbacks(t *testing.T) {
+	tests := []struct {
+		name          string
+		following     bool
+		data          string
+		wantAnswer    string
+		wantButton    string
+		wantFollowing bool
+	}{
+		{"follow", false, "follow:" + glassKingdom, "You are now following Glass Kingdom", "unfollow:" + glassKingdom, true},
+		{"unfollow", true, "unfollow:" + glassKingdom, "You have unfollowed Glass Kingdom", "follow:" + glassKingdom, false},
+		{"follow unknown show", false, "follow:tmdb_404", "An error occurred while following the show.", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := memory.New()
+			seedFollower(t, store, 1)
+
+			if !tt.following {
+				must(t, store.UnfollowShow(1, glassKingdom))
+			}
+
+			server := startBot(t, store)
+			server.Press(telegramtest.User(1), 1, tt.data)
+
+			waitCall(t, server, "answerCallbackQuery", func(call telegramtest.Call) bool {
+				return call.Params.Get("text") == tt.wantAnswer
+			})
+
+			if tt.wantButton != "" {
+				waitCall(t, server, "editMessageText", func(call telegramtest.Call) bool {
+					return call.ChatID() == 1 && hasButton(call, tt.wantButton)
+				})
+			}
+
+			following, err := store.IsUserFollowingShow(1, glassKingdom)
+			must(t, err)
+
+			if following != tt.wantFollowing {
+				t.Errorf("following = %t, want %t", following, tt.wantFollowing)
+			}
+		})
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T, store *memory.Store)
+		wantAlert bool
+	}{
+		{"following", func(*testing.T, *memory.Store) {}, true},
+		{"muted", func(t *testing.T, store *memory.Store) {
+			must(t, store.SetShowMuted(1, glassKingdom, true))
+		}, false},
+		{"blocked the bot", func(t *testing.T, store *memory.Store) {
+			must(t, store.SetUserActive(1, false))
+		}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			store := memory.New()
+			seedFollower(t, store, 1)
+			tt.setup(t, store)
+
+			// User 2 always gets the alert, so its arrival marks the end of the startup run.
+			seedFollower(t, store, 2)
+
+			server := startBot(t, store)
+
+			alert := waitCall(t, server, "sendMessage", sentTo(2, "New Episode Alert"))
+			if !strings.Contains(alert.Text(), "Cracks") {
+				t.Errorf("alert = %q, want the Cracks episode", alert.Text())
+			}
+
+			waitOutboxDrained(t, store)
+
+			sent := slices.ContainsFunc(server.Calls("sendMessage"), sentTo(1, "New Episode Alert"))
+			if sent != tt.wantAlert {
+				t.Errorf("alert sent to user 1 = %t, want %t", sent, tt.wantAlert)
+			}
+		})
+	}
+}
+
+// waitOutboxDrained waits until every queued message was handed to Telegram.
+func waitOutboxDrained(t *testing.T, store *memory.Store) {
+	t.Helper()
+
+	deadline := time.Now().Add(telegramtest.WaitTimeout)
+
+	for {
+		pending, err := store.GetDueOutboxMessages(1)
+		must(t, err)
+
+		if len(pending) == 0 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("outbox still has messages after %s", telegramtest.WaitTimeout)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,272 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"gorm.io/gorm"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+const (
+	calendarPath    = "/calendar/"
+	calendarFileExt = ".ics"
+
+	// calendarTokenBytes of randomness make feed URLs unguessable.
+	calendarTokenBytes = 24
+
+	// calendarUIDDomain qualifies event UIDs so they stay unique across calendars.
+	calendarUIDDomain = "shows-bot"
+
+	// calendarEventDuration is the length of timed events; providers don't report runtimes.
+	calendarEventDuration = time.Hour
+
+	// calendarRefreshInterval is how often clients are asked to re-fetch the feed.
+	calendarRefreshInterval = "PT6H"
+
+	defaultCalendarPastDays = 30
+
+	icsDateLayout     = "20060102"
+	icsDateTimeLayout = "20060102T150405Z"
+
+	// icsLineLimit is the longest content line, in octets, before it has to be folded.
+	icsLineLimit = 75
+)
+
+var icsTextEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "")
+
+func newCalendarToken() (string, error) {
+	token := make([]byte, calendarTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate calendar token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+func (b *Bot) calendarURL(token string) string {
+	return strings.TrimRight(b.config.Calendar.BaseURL, "/") + calendarPath + token + calendarFileExt
+}
+
+func (b *Bot) handleCalendarCommand(message *tgbotapi.Message) {
+	// The link is a secret and creating it issues a token, so in groups it is for admins only.
+	if !b.canManage(message.Chat, message.From) {
+		b.sendMessage(message.Chat.ID, "Only group admins can get the calendar link.")
+
+		return
+	}
+
+	text, markup, err := b.calendarMenu(int64(subscriberID(message.Chat)), false)
+	if err != nil {
+		slog.Error("Error loading calendar link", "err", err)
+		b.sendMessage(message.Chat.ID, "An error occurred while creating your calendar link.")
+
+		return
+	}
+
+	b.sendMessageWithMarkup(message.Chat.ID, text, markup)
+}
+
+// displayCalendar shows the user's feed link in place of messageID, replacing it with a new one when rotate is set.
+func (b *Bot) displayCalendar(chatID int64, messageID int, userID int64, rotate bool) error {
+	text, markup, err := b.calendarMenu(userID, rotate)
+	if err != nil {
+		return err
+	}
+
+	b.editMessageWithMenu(chatID, messageID, text, markup)
+
+	return nil
+}
+
+// calendarMenu returns the user's feed link, creating the secret token on first use or when rotate is set.
+func (b *Bot) calendarMenu(userID int64, rotate bool) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	if !b.config.Calendar.Enabled {
+		return "Calendar feeds are not enabled on this bot.", tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...), nil
+	}
+
+	user, err := b.dbManager.GetUser(userID)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	var token string
+	if user.CalendarToken != nil {
+		token = *user.CalendarToken
+	}
+
+	if token == "" || rotate {
+		if token, err = newCalendarToken(); err != nil {
+			return "", tgbotapi.InlineKeyboardMarkup{}, err
+		}
+
+		if err = b.dbManager.SetCalendarToken(userID, token); err != nil {
+			return "", tgbotapi.InlineKeyboardMarkup{}, err
+		}
+	}
+
+	text := fmt.Sprintf(`📆 *Calendar Feed*
+
+Subscribe to this link in Google Calendar, Apple Calendar or Outlook to see the episodes of the shows you follow:
+
+%s
+
+The calendar updates by itself when episodes are added or rescheduled. Keep the link private: anyone who has it can see your shows. Get a new link if it leaks; the old one stops working.`,
+		b.calendarURL(token))
+
+	markup := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔄 New link", fmt.Sprintf("%s:rotate", ActionCalendar)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏠 Home", MenuMain),
+		),
+	)
+
+	return text, markup, nil
+}
+
+// handleCalendarFeed serves GET /calendar/<token>.ics; unknown tokens get a 404 so rotated links stop working.
+func (b *Bot) handleCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutSuffix(r.PathValue("file"), calendarFileExt)
+	if !ok || token == "" {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	user, err := b.dbManager.GetUserByCalendarToken(token)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if err != nil {
+		slog.Error("Error looking up calendar token", "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	pastDays := b.config.Calendar.PastDays
+	if pastDays <= 0 {
+		pastDays = defaultCalendarPastDays
+	}
+
+	now := time.Now().UTC()
+
+	episodes, err := b.dbManager.GetCalendarEpisodes(user.ID, now.AddDate(0, 0, -pastDays))
+	if err != nil {
+		slog.Error("Error getting calendar episodes", "userID", user.ID, "err", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if err = writeCalendar(w, episodes, now); err != nil {
+		slog.Error("Error writing calendar feed", "userID", user.ID, "err", err)
+	}
+}
+
+// writeCalendar writes the episodes as an iCalendar (RFC 5545) feed. Event UIDs derive from episode IDs,
+// so a rescheduled episode replaces its old event in subscribed calendars.
+func writeCalendar(out io.Writer, episodes []models.Episode, now time.Time) error {
+	w := &icsWriter{out: out}
+
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//shows_bot//Episode Calendar//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", "TV Shows")
+	w.line("REFRESH-INTERVAL;VALUE=DURATION", calendarRefreshInterval)
+	w.line("X-PUBLISHED-TTL", calendarRefreshInterval)
+
+	for _, episode := range episodes {
+		w.line("BEGIN", "VEVENT")
+		w.line("UID", fmt.Sprintf("%s@%s", episode.ID, calendarUIDDomain))
+		w.line("DTSTAMP", now.UTC().Format(icsDateTimeLayout))
+
+		if !episode.UpdatedAt.IsZero() {
+			w.line("LAST-MODIFIED", episode.UpdatedAt.UTC().Format(icsDateTimeLayout))
+		}
+
+		if episode.HasAirTime() {
+			start := episode.AirStamp.UTC()
+			w.line("DTSTART", start.Format(icsDateTimeLayout))
+			w.line("DTEND", start.Add(calendarEventDuration).Format(icsDateTimeLayout))
+		} else {
+			// AirDate is a calendar date stored as UTC midnight, so it becomes an all-day event.
+			day := episode.AirDate.UTC()
+			w.line("DTSTART;VALUE=DATE", day.Format(icsDateLayout))
+			w.line("DTEND;VALUE=DATE", day.AddDate(0, 0, 1).Format(icsDateLayout))
+		}
+
+		w.line("SUMMARY", icsText(fmt.Sprintf("%s %s: %s", episode.Show.Name, episodeCode(episode), episode.Name)))
+
+		if overview := strings.TrimSpace(stripHTMLTags(episode.Overview)); overview != "" {
+			w.line("DESCRIPTION", icsText(overview))
+		}
+
+		w.line("END", "VEVENT")
+	}
+
+	w.line("END", "VCALENDAR")
+
+	return w.err
+}
+
+func icsText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
+
+// icsWriter writes CRLF-terminated content lines, folding them at icsLineLimit octets
+// without splitting UTF-8 sequences. The first write error is kept and later writes are skipped.
+type icsWriter struct {
+	out io.Writer
+	err error
+}
+
+func (w *icsWriter) line(name, value string) {
+	if w.err != nil {
+		return
+	}
+
+	var sb strings.Builder
+
+	rest := name + ":" + value
+	limit := icsLineLimit
+
+	for len(rest) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(rest[cut]) {
+			cut--
+		}
+
+		sb.WriteString(rest[:cut])
+		sb.WriteString("\r\n ")
+
+		rest = rest[cut:]
+		// Continuation lines start with a space, which counts toward the limit.
+		limit = icsLineLimit - 1
+	}
+
+	sb.WriteString(rest)
+	sb.WriteString("\r\n")
+
+	_, w.err = io.WriteString(w.out, sb.String())
+}
@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+func TestWriteCalendarFolding(t *testing.T) {
+	tests := []struct {
+		name        string
+		showName    string
+		episodeName string
+		wantSummary string
+	}{
+		{
+			name:        "ascii",
+			showName:    "Night Shift Detectives",
+			episodeName: strings.Repeat("Long Title ", 12),
+			wantSummary: "Night Shift Detectives S02E03: " + strings.Repeat("Long Title ", 12),
+		},
+		{
+			name:        "two-byte runes",
+			showName:    "Ночная смена",
+			episodeName: strings.Repeat("Очень длинное название ", 5),
+			wantSummary: "Ночная смена S02E03: " + strings.Repeat("Очень длинное название ", 5),
+		},
+		{
+			name:        "three-byte runes",
+			showName:    "深夜の探偵",
+			episodeName: strings.Repeat("とても長いエピソードの題名", 4),
+			wantSummary: "深夜の探偵 S02E03: " + strings.Repeat("とても長いエピソードの題名", 4),
+		},
+		{
+			name:        "four-byte runes and escapes",
+			showName:    "Glass; Kingdom",
+			episodeName: strings.Repeat("🎬📺, ", 20),
+			wantSummary: `Glass\; Kingdom S02E03: ` + strings.Repeat(`🎬📺\, `, 20),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			episode := models.Episode{
+				ID:            "tvmaze_1",
+				Name:          tt.episodeName,
+				SeasonNumber:  2,
+				EpisodeNumber: 3,
+				AirDate:       time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC),
+				Show:          models.Show{Name: tt.showName},
+			}
+
+			var out bytes.Buffer
+			if err := writeCalendar(&out, []models.Episode{episode}, time.Now()); err != nil {
+				t.Fatal(err)
+			}
+
+			feed := out.String()
+			if !strings.HasSuffix(feed, "\r\n") {
+				t.Fatal("feed does not end with CRLF")
+			}
+
+			for _, line := range strings.Split(strings.TrimSuffix(feed, "\r\n"), "\r\n") {
+				if len(line) > icsLineLimit {
+					t.Errorf("line of %d octets: %q", len(line), line)
+				}
+
+				if !utf8.ValidString(line) {
+					t.Errorf("folding split a rune: %q", line)
+				}
+
+				if strings.ContainsAny(line, "\r\n") {
+					t.Errorf("bare line break in %q", line)
+				}
+			}
+
+			unfolded := strings.ReplaceAll(feed, "\r\n ", "")
+			if !strings.Contains(unfolded, "\r\nSUMMARY:"+tt.wantSummary+"\r\n") {
+				t.Errorf("unfolded feed lacks SUMMARY:%s\n%s", tt.wantSummary, unfolded)
+			}
+		})
+	}
+}
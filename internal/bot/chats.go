@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+// groupAnonymousBotID is the sender Telegram shows for messages of anonymous group admins.
+const groupAnonymousBotID = 1087968824
+
+// managedActions change what a chat follows or how it is reminded, so in groups only admins may use them.
+var managedActions = map[string]bool{
+	ActionFollow:      true,
+	ActionUnfollow:    true,
+	ActionWatch:       true,
+	ActionUnwatch:     true,
+	ActionWatchSeason: true,
+	ActionWatchUpTo:   true,
+	ActionCalendar:    true,
+	ActionLead:        true,
+	ActionQuiet:       true,
+	ActionDigest:      true,
+	ActionMute:        true,
+}
+
+// subscriberID is who follows shows from chat: the user in a private chat, whose chat ID is
+// their user ID, and the chat itself in groups.
+func subscriberID(chat *tgbotapi.Chat) int {
+	return int(chat.ID)
+}
+
+// subscriber is the record stored for the sender of message.
+func subscriber(message *tgbotapi.Message) models.User {
+	if message.Chat.IsPrivate() {
+		return models.FromTelegramUser(message.From)
+	}
+
+	return models.FromTelegramChat(message.Chat)
+}
+
+// botCommand returns the message's command without its @username suffix. addressed is set when the
+// suffix names this bot; commands for other bots come back empty.
+func (b *Bot) botCommand(message *tgbotapi.Message) (command string, addressed bool) {
+	command, target, found := strings.Cut(message.CommandWithAt(), "@")
+	if !found {
+		return command, false
+	}
+
+	if !strings.EqualFold(target, b.api.Username()) {
+		return "", false
+	}
+
+	return command, true
+}
+
+// canManage reports whether user may change what chat follows: anyone in a private chat, admins in groups.
+func (b *Bot) canManage(chat *tgbotapi.Chat, user *tgbotapi.User) bool {
+	if chat.IsPrivate() || user.ID == groupAnonymousBotID {
+		return true
+	}
+
+	return b.isChatAdmin(tgbotapi.ChatConfigWithUser{ChatID: chat.ID, UserID: user.ID})
+}
+
+func (b *Bot) isChatAdmin(config tgbotapi.ChatConfigWithUser) bool {
+	member, err := b.api.GetChatMember(config)
+	if err != nil {
+		slog.Error("Error checking chat admin", "chatID", config.ChatID, "userID", config.UserID, "err", err)
+
+		return false
+	}
+
+	return member.IsCreator() || member.IsAdministrator()
+}
+
+// notificationChat is where userID's reminders and digests are posted: their linked channel, if any.
+func (b *Bot) notificationChat(userID int64) int64 {
+	user, err := b.dbManager.GetUser(userID)
+	if err != nil {
+		slog.Debug("Posting notifications to the subscriber's chat", "userID", userID, "err", err)
+
+		return userID
+	}
+
+	if user.NotifyChatID != nil {
+		return *user.NotifyChatID
+	}
+
+	return userID
+}
+
+// handleChannelCommand shows, links or unlinks the channel the chat's notifications are posted to:
+// /channel @name, /channel <id> or /channel off.
+func (b *Bot) handleChannelCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	arg := strings.TrimSpace(message.CommandArguments())
+
+	if arg == "" {
+		b.sendMessage(chatID, b.channelStatus(int64(subscriberID(message.Chat))))
+
+		return
+	}
+
+	if !b.canManage(message.Chat, message.From) {
+		b.sendMessage(chatID, "Only group admins can change where notifications are posted.")
+
+		return
+	}
+
+	if strings.EqualFold(arg, "off") {
+		if err := b.dbManager.SetNotifyChat(int64(subscriberID(message.Chat)), nil); err != nil {
+			slog.Error("Error unlinking channel", "chatID", chatID, "err", err)
+			b.sendMessage(chatID, "An error occurred while saving the channel.")
+
+			return
+		}
+
+		b.sendMessage(chatID, "🔔 Notifications are posted here again.")
+
+		return
+	}
+
+	config, ok := channelConfig(arg)
+	if !ok {
+		b.sendMessage(chatID, "Use /channel @channelname or /channel followed by the channel ID, or /channel off.")
+
+		return
+	}
+
+	channel, err := b.api.GetChat(config)
+	if err != nil || !channel.IsChannel() {
+		b.sendMessage(chatID, "I couldn't find that channel. Add me to it as an admin first, then try again.")
+
+		return
+	}
+
+	if !b.isChatAdmin(tgbotapi.ChatConfigWithUser{ChatID: channel.ID, UserID: message.From.ID}) {
+		b.sendMessage(chatID, fmt.Sprintf("Only admins of %s can link it.", channel.Title))
+
+		return
+	}
+
+	// The confirmation doubles as a check that the bot may post in the channel.
+	confirmation := b.newMessage(channel.ID, "🔔 New episodes of followed shows will be posted here.")
+	if _, err = b.sender.sendNow(channel.ID, confirmation, laneInteractive); err != nil {
+		slog.Info("Cannot post to channel", "channelID", channel.ID, "err", err)
+		b.sendMessage(chatID, fmt.Sprintf("I can't post in %s. Make me an admin there who can post messages.", channel.Title))
+
+		return
+	}
+
+	if err = b.dbManager.SetNotifyChat(int64(subscriberID(message.Chat)), &channel.ID); err != nil {
+		slog.Error("Error linking channel", "chatID", chatID, "channelID", channel.ID, "err", err)
+		b.sendMessage(chatID, "An error occurred while saving the channel.")
+
+		return
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf(
+		"🔔 Reminders and digests are now posted to %s. Use /channel off to get them here again.", channel.Title))
+}
+
+func (b *Bot) channelStatus(userID int64) string {
+	const usage = "Link a channel with /channel @channelname; I need to be an admin there who can post messages."
+
+	user, err := b.dbManager.GetUser(userID)
+	if err != nil || user.NotifyChatID == nil {
+		return "🔔 Notifications are posted in this chat.\n\n" + usage
+	}
+
+	name := strconv.FormatInt(*user.NotifyChatID, 10)
+	if channel, err := b.api.GetChat(tgbotapi.ChatConfig{ChatID: *user.NotifyChatID}); err == nil {
+		name = channel.Title
+	}
+
+	return fmt.Sprintf("🔔 Notifications are posted to %s.\n\nUse /channel off to get them here again.", name)
+}
+
+// channelConfig parses a channel given as @username or numeric chat ID.
+func channelConfig(arg string) (tgbotapi.ChatConfig, bool) {
+	if strings.HasPrefix(arg, "@") && len(arg) > 1 {
+		return tgbotapi.ChatConfig{SuperGroupUsername: arg}, true
+	}
+
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return tgbotapi.ChatConfig{}, false
+	}
+
+	return tgbotapi.ChatConfig{ChatID: id}, true
+}
+
+// unlinkChannel stops posting to a channel the bot can no longer reach, so notifications go back
+// to the subscribers' own chats. It reports whether chatID was a linked channel.
+func (b *Bot) unlinkChannel(chatID int64) bool {
+	unlinked, err := b.dbManager.ClearNotifyChat(chatID)
+	if err != nil {
+		slog.Error("Error unlinking channel", "chatID", chatID, "err", err)
+	}
+
+	if unlinked == 0 {
+		return false
+	}
+
+	slog.Info("Unlinked unreachable channel", "chatID", chatID, "subscribers", unlinked)
+
+	return true
+}
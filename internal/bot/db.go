@@ -1,27 +1,61 @@
 package bot
 
 import (
+	"time"
+
 	"github.com/dkhalizov/shows/internal/models"
 )
 
 type Operations interface {
 	Init() error
+	Close() error
 
 	StoreShow(show *models.Show) (string, error)
 	GetShow(id string) (show *models.Show, err error)
+	GetShowByIMDbID(imdbID string) (*models.Show, error)
+	UpdateShowDetails(details *models.Show) (oldStatus string, err error)
 	FollowShow(userID int, showID string) error
 	UnfollowShow(userID int, showID string) error
 	IsUserFollowingShow(userID int, showID string) (bool, error)
 	GetUserShows(userID int) ([]models.Show, error)
 
 	StoreUser(tgUser models.User) error
+	GetUser(userID int64) (*models.User, error)
+	SetUserTimezone(userID int64, timezone string) error
+	SetUserActive(userID int64, active bool) error
+	SetCalendarToken(userID int64, token string) error
+	GetUserByCalendarToken(token string) (*models.User, error)
+	SetNotifyChat(userID int64, chatID *int64) error
+	ClearNotifyChat(chatID int64) (int64, error)
 	GetAllFollowedShows() ([]string, error)
-	GetUsersToNotify(episodeID, showID string) ([]int64, error)
-	RecordNotification(userID int64, episodeID string) error
+	GetUsersToNotify(episode *models.Episode, defaultLead time.Duration) ([]models.Reminder, error)
+	RecordNotification(userID int64, episodeID, kind string) error
 	IsShowFollowed(userID int64, showID string) (bool, error)
+	GetShowFollowers(showID string) ([]int64, error)
+	ClearNotifications(episodeID string, kinds []string) error
+
+	GetUserSettings(userID int64) (*models.UserSettings, error)
+	SaveUserSettings(settings *models.UserSettings) error
+	SetShowMuted(userID int64, showID string, muted bool) error
+	GetMutedShows(userID int64) ([]string, error)
+	GetDigestSubscribers() ([]models.UserSettings, error)
+	GetEpisodesForDigest(userID int64, from, to time.Time) ([]models.Episode, error)
+	RecordDigest(userID int64, episodeIDs []string, msg *models.OutboxMessage) error
 
-	StoreEpisode(episode *models.Episode) (string, error)
+	EnqueueMessage(msg *models.OutboxMessage) error
+	EnqueueNotification(userID int64, msg *models.OutboxMessage, episodeID string, kinds []string) error
+	GetDueOutboxMessages(limit int) ([]models.OutboxMessage, error)
+	UpdateOutboxMessage(msg *models.OutboxMessage) error
+
+	StoreEpisode(episode *models.Episode) (*models.EpisodeChange, error)
+	GetEpisodeChanges(episodeID string) ([]models.EpisodeChange, error)
 	GetNextEpisode(showID string) (*models.Episode, error)
 	GetUpcomingEpisodesForUser(userID int) ([]models.Episode, error)
+	GetCalendarEpisodes(userID int64, from time.Time) ([]models.Episode, error)
 	GetEpisodesForShow(showID string) ([]models.Episode, error)
+	GetEpisode(id string) (*models.Episode, error)
+
+	MarkEpisodesWatched(userID int64, episodeIDs []string) error
+	UnmarkEpisodeWatched(userID int64, episodeID string) error
+	GetWatchedEpisodes(userID int64, showID string) ([]string, error)
 }
@@ -0,0 +1,141 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+	"gorm.io/gorm"
+
+	"github.com/dkhalizov/shows/clients"
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+// Payloads of t.me/<bot>?start=... links, passed to /start.
+const (
+	// startShowPrefix opens a stored show by its ID, e.g. show_tvmaze_1001.
+	startShowPrefix = "show_"
+	// startIMDbPrefix opens a show by its IMDb ID, e.g. imdb_tt0903747.
+	startIMDbPrefix = "imdb_"
+	startInline     = "inline"
+)
+
+var (
+	// startPayloadPattern is what Telegram accepts as a start parameter.
+	startPayloadPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+	imdbIDPattern       = regexp.MustCompile(`^tt\d+$`)
+)
+
+// startLink returns a t.me link that opens the bot with /start payload, or "" when the bot's username is unknown.
+func (b *Bot) startLink(payload string) string {
+	username := b.api.Username()
+	if username == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("https://t.me/%s?start=%s", username, url.QueryEscape(payload))
+}
+
+// showStartPayload is the /start payload that opens show, or "" when it has none.
+func showStartPayload(show *models.Show) string {
+	if payload := startShowPrefix + show.ID; startPayloadPattern.MatchString(payload) {
+		return payload
+	}
+
+	if payload := startIMDbPrefix + show.IMDbID; show.IMDbID != "" && startPayloadPattern.MatchString(payload) {
+		return payload
+	}
+
+	return ""
+}
+
+// shareLink opens Telegram's share dialog with a link that opens show in the bot.
+func (b *Bot) shareLink(show *models.Show) string {
+	payload := showStartPayload(show)
+	if payload == "" {
+		return ""
+	}
+
+	link := b.startLink(payload)
+	if link == "" {
+		return ""
+	}
+
+	query := url.Values{"url": {link}, "text": {show.Name}}
+
+	return "https://t.me/share/url?" + query.Encode()
+}
+
+// handleStartPayload opens the show a deep link points to. It reports whether the payload was a show link,
+// so that other payloads fall through to the welcome message.
+func (b *Bot) handleStartPayload(ctx context.Context, message *tgbotapi.Message, payload string) bool {
+	var (
+		show *models.Show
+		err  error
+	)
+
+	switch {
+	case strings.HasPrefix(payload, startShowPrefix):
+		show, err = b.dbManager.GetShow(strings.TrimPrefix(payload, startShowPrefix))
+	case strings.HasPrefix(payload, startIMDbPrefix):
+		show, err = b.showByIMDbID(ctx, strings.TrimPrefix(payload, startIMDbPrefix))
+	default:
+		return false
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, clients.ErrNotFound) {
+		b.sendMessageWithMarkup(message.Chat.ID,
+			"I couldn't find the show this link points to. Type a show name to search for it.", b.createMainMenu())
+
+		return true
+	}
+
+	if err != nil {
+		slog.Error("Error opening show link", "payload", payload, "err", err)
+		b.sendMessageWithMarkup(message.Chat.ID, "An error occurred while fetching show details.", b.createMainMenu())
+
+		return true
+	}
+
+	details, markup := b.showDetails(show, subscriberID(message.Chat), BackMyShows)
+	b.sendMessageWithMarkup(message.Chat.ID, details, markup)
+
+	return true
+}
+
+// showByIMDbID returns the stored show with imdbID, looking it up at the providers and storing it
+// when no follower has brought it in yet.
+func (b *Bot) showByIMDbID(ctx context.Context, imdbID string) (*models.Show, error) {
+	if !imdbIDPattern.MatchString(imdbID) {
+		return nil, clients.ErrNotFound
+	}
+
+	show, err := b.dbManager.GetShowByIMDbID(imdbID)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return show, err
+	}
+
+	for _, provider := range slices.Sorted(maps.Keys(b.apiClients)) {
+		found, err := b.apiClients[provider].FindByIMDbID(ctx, imdbID)
+		if errors.Is(err, clients.ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			slog.Error("Error looking up IMDb ID", "provider", provider, "imdbID", imdbID, "err", err)
+
+			continue
+		}
+
+		return b.storeImportedShow(found)
+	}
+
+	return nil, clients.ErrNotFound
+}
@@ -0,0 +1,215 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+// digestHours are the send times the settings menu cycles through.
+var digestHours = []int{7, 8, 9, 12, 18, 20}
+
+const maxDigestButtons = 20
+
+// sendDueDigests sends every digest whose scheduled time has passed since the last one.
+func (b *Bot) sendDueDigests(ctx context.Context) {
+	subscribers, err := b.dbManager.GetDigestSubscribers()
+	if err != nil {
+		slog.Error("Error querying digest subscribers", "err", err)
+
+		return
+	}
+
+	now := time.Now()
+
+	for i := range subscribers {
+		if ctx.Err() != nil {
+			return
+		}
+
+		settings := &subscribers[i]
+		loc := b.userLocation(settings.UserID)
+
+		if !settings.DigestDue(now, loc) {
+			continue
+		}
+
+		if err = b.sendDigest(settings, loc, now); err != nil {
+			slog.Error("Error sending digest", "userID", settings.UserID, "err", err)
+		}
+	}
+}
+
+// sendDigest batches the user's episodes for the coming period into one queued message. Listed
+// episodes are recorded in notifications, so no later digest or reminder repeats them.
+func (b *Bot) sendDigest(settings *models.UserSettings, loc *time.Location, now time.Time) error {
+	episodes, err := b.dbManager.GetEpisodesForDigest(settings.UserID, now, now.Add(settings.DigestPeriod()))
+	if err != nil {
+		return fmt.Errorf("could not get digest episodes: %w", err)
+	}
+
+	episodeIDs := make([]string, len(episodes))
+	for i, episode := range episodes {
+		episodeIDs[i] = episode.ID
+	}
+
+	var msg *models.OutboxMessage
+
+	if len(episodes) > 0 {
+		slog.Info("Queueing digest", "userID", settings.UserID, "digest", settings.Digest, "episodes", len(episodes))
+
+		chatID := b.notificationChat(settings.UserID)
+		text, markup := digestMessage(settings, episodes, loc)
+
+		// Channel posts can't open the bot's menus.
+		replyMarkup := &markup
+		if chatID != settings.UserID {
+			replyMarkup = nil
+		}
+
+		if msg, err = newOutboxMessage(chatID, text, replyMarkup); err != nil {
+			return err
+		}
+	}
+
+	return b.dbManager.RecordDigest(settings.UserID, episodeIDs, msg)
+}
+
+func digestMessage(
+	settings *models.UserSettings,
+	episodes []models.Episode,
+	loc *time.Location,
+) (string, tgbotapi.InlineKeyboardMarkup) {
+	title := "🗞 *Your Daily Digest*"
+	if settings.Digest == models.DigestWeekly {
+		title = "🗞 *Your Weekly Digest*"
+	}
+
+	var days []string
+
+	byDay := make(map[string][]models.Episode)
+
+	for _, episode := range episodes {
+		day := episode.AirDate.Format("Monday, January 2")
+		if episode.HasAirTime() {
+			day = episode.AirStamp.In(loc).Format("Monday, January 2")
+		}
+
+		if _, ok := byDay[day]; !ok {
+			days = append(days, day)
+		}
+
+		byDay[day] = append(byDay[day], episode)
+	}
+
+	text := title
+
+	var showIDs []string
+
+	showNames := make(map[string]string)
+
+	for _, day := range days {
+		text += fmt.Sprintf("\n\n📅 *%s*", day)
+
+		var dayShows []string
+
+		byShow := make(map[string][]models.Episode)
+
+		for _, episode := range byDay[day] {
+			if _, ok := byShow[episode.ShowID]; !ok {
+				dayShows = append(dayShows, episode.ShowID)
+			}
+
+			byShow[episode.ShowID] = append(byShow[episode.ShowID], episode)
+			showNames[episode.ShowID] = episode.Show.Name
+		}
+
+		for _, showID := range dayShows {
+			text += fmt.Sprintf("\n*%s*", showNames[showID])
+
+			for _, episode := range byShow[showID] {
+				text += fmt.Sprintf("\n• S%02dE%02d: %s", episode.SeasonNumber, episode.EpisodeNumber, episode.Name)
+
+				if episode.HasAirTime() {
+					text += fmt.Sprintf(" at %s", episode.AirStamp.In(loc).Format("15:04 MST"))
+				}
+			}
+
+			if !slices.Contains(showIDs, showID) {
+				showIDs = append(showIDs, showID)
+			}
+		}
+	}
+
+	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
+
+	for _, showID := range showIDs[:min(len(showIDs), maxDigestButtons)] {
+		inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("📋 %s", showNames[showID]),
+				fmt.Sprintf("%s:%s", ActionDetails, showID),
+			),
+		))
+	}
+
+	inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🏠 Home", MenuMain),
+	))
+
+	return text, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...)
+}
+
+func formatDigest(settings *models.UserSettings) string {
+	switch settings.Digest {
+	case models.DigestDaily:
+		return fmt.Sprintf("daily at %02d:00", settings.DigestHour)
+	case models.DigestWeekly:
+		return fmt.Sprintf("%ss at %02d:00", settings.DigestWeekday, settings.DigestHour)
+	default:
+		return "off"
+	}
+}
+
+// cycleDigest steps one digest setting (mode, hour or weekday) to its next value.
+func (b *Bot) cycleDigest(userID int64, field string) error {
+	settings, err := b.dbManager.GetUserSettings(userID)
+	if err != nil {
+		return err
+	}
+
+	switch field {
+	case "mode":
+		switch settings.Digest {
+		case models.DigestOff:
+			settings.Digest = models.DigestDaily
+		case models.DigestDaily:
+			settings.Digest = models.DigestWeekly
+		default:
+			settings.Digest = models.DigestOff
+		}
+	case "hour":
+		next := digestHours[0]
+
+		for _, hour := range digestHours {
+			if hour > settings.DigestHour {
+				next = hour
+
+				break
+			}
+		}
+
+		settings.DigestHour = next
+	case "day":
+		settings.DigestWeekday = (settings.DigestWeekday + 1) % 7
+	default:
+		return fmt.Errorf("unknown digest setting %q", field)
+	}
+
+	return b.dbManager.SaveUserSettings(settings)
+}
@@ -0,0 +1,313 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+const (
+	// episodesPerPage keeps a season page readable and well under Telegram's button limit.
+	episodesPerPage = 10
+
+	seasonsPerRow = 3
+
+	// specialsSeason is the season number providers file specials under.
+	specialsSeason = 0
+)
+
+func seasonLabel(season int) string {
+	if season == specialsSeason {
+		return "Specials"
+	}
+
+	return fmt.Sprintf("Season %d", season)
+}
+
+func episodeCode(episode models.Episode) string {
+	return fmt.Sprintf("S%02dE%02d", episode.SeasonNumber, episode.EpisodeNumber)
+}
+
+// episodeAirLabel formats the episode's air time, or TBA when the provider has no date yet.
+func episodeAirLabel(episode *models.Episode, loc *time.Location, layout string) string {
+	if episode.AirTime().IsZero() {
+		return "TBA"
+	}
+
+	return formatEpisodeAirTime(episode, loc, layout)
+}
+
+// seasonCallback is the callback data of one page of a season's episode list.
+func seasonCallback(showID string, season, page int) string {
+	return fmt.Sprintf("%s:%s:%d:%d", ActionSeason, showID, season, page)
+}
+
+// parseSeasonParam splits the parameter of a seasonCallback.
+func parseSeasonParam(param string) (showID string, season, page int, err error) {
+	parts := strings.Split(param, ":")
+	if len(parts) != 3 {
+		return "", 0, 0, fmt.Errorf("invalid season parameter %q", param)
+	}
+
+	if season, err = strconv.Atoi(parts[1]); err != nil {
+		return "", 0, 0, err
+	}
+
+	if page, err = strconv.Atoi(parts[2]); err != nil {
+		return "", 0, 0, err
+	}
+
+	return parts[0], season, page, nil
+}
+
+// seasonsOf returns the show's seasons in order, with specials last.
+func seasonsOf(episodes []models.Episode) (seasons []int, counts map[int]int) {
+	counts = make(map[int]int)
+
+	for _, episode := range episodes {
+		if counts[episode.SeasonNumber] == 0 {
+			seasons = append(seasons, episode.SeasonNumber)
+		}
+
+		counts[episode.SeasonNumber]++
+	}
+
+	slices.SortFunc(seasons, func(a, b int) int {
+		switch {
+		case a == b:
+			return 0
+		case a == specialsSeason:
+			return 1
+		case b == specialsSeason:
+			return -1
+		default:
+			return a - b
+		}
+	})
+
+	return seasons, counts
+}
+
+// displayShowEpisodes shows the season picker of a show.
+func (b *Bot) displayShowEpisodes(chatID int64, messageID int, showID string) {
+	show, err := b.dbManager.GetShow(showID)
+	if err != nil {
+		slog.Error("Error getting show", "showID", showID, "err", err)
+		b.showEpisodesError(chatID, messageID)
+
+		return
+	}
+
+	episodes, err := b.dbManager.GetEpisodesForShow(showID)
+	if err != nil {
+		slog.Error("Error getting episodes", "err", err)
+		b.showEpisodesError(chatID, messageID)
+
+		return
+	}
+
+	if len(episodes) == 0 {
+		b.editMessageWithMenu(
+			chatID,
+			messageID,
+			"No episodes found for this show.",
+			tgbotapi.NewInlineKeyboardMarkup(b.createBackHomeRow(fmt.Sprintf("%s:%s", ActionDetails, showID))),
+		)
+
+		return
+	}
+
+	seasons, counts := seasonsOf(episodes)
+	text := fmt.Sprintf("📋 *All Episodes*\n\n*%s*\n\n%d episodes in %d seasons. Pick a season:", show.Name, len(episodes), len(seasons))
+
+	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
+
+	var row []tgbotapi.InlineKeyboardButton
+
+	for _, season := range seasons {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s (%d)", seasonLabel(season), counts[season]),
+			seasonCallback(showID, season, 0),
+		))
+
+		if len(row) == seasonsPerRow {
+			inlineKeyboard = append(inlineKeyboard, row)
+			row = nil
+		}
+	}
+
+	if len(row) > 0 {
+		inlineKeyboard = append(inlineKeyboard, row)
+	}
+
+	inlineKeyboard = append(inlineKeyboard, b.createBackHomeRow(fmt.Sprintf("%s:%s", ActionDetails, showID)))
+
+	b.editMessageWithMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...))
+}
+
+// displaySeason shows one page of a season's episodes, each with a button to its details.
+func (b *Bot) displaySeason(chatID int64, messageID int, showID string, season, page, userID int) {
+	show, err := b.dbManager.GetShow(showID)
+	if err != nil {
+		slog.Error("Error getting show", "showID", showID, "err", err)
+		b.showEpisodesError(chatID, messageID)
+
+		return
+	}
+
+	all, err := b.dbManager.GetEpisodesForShow(showID)
+	if err != nil {
+		slog.Error("Error getting episodes", "err", err)
+		b.showEpisodesError(chatID, messageID)
+
+		return
+	}
+
+	watched, err := b.watchedSet(int64(userID), showID)
+	if err != nil {
+		slog.Error("Error getting watched episodes", "err", err)
+	}
+
+	var episodes []models.Episode
+
+	for _, episode := range all {
+		if episode.SeasonNumber == season {
+			episodes = append(episodes, episode)
+		}
+	}
+
+	pages := max(1, (len(episodes)+episodesPerPage-1)/episodesPerPage)
+	page = max(0, min(page, pages-1))
+	start := page * episodesPerPage
+	end := min(start+episodesPerPage, len(episodes))
+
+	loc := b.userLocation(int64(userID))
+	text := fmt.Sprintf("📋 *%s* – %s\n", show.Name, seasonLabel(season))
+
+	if pages > 1 {
+		text += fmt.Sprintf("Page %d of %d\n", page+1, pages)
+	}
+
+	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
+
+	for _, episode := range episodes[start:end] {
+		mark := ""
+		if watched[episode.ID] {
+			mark = "✅ "
+		}
+
+		text += fmt.Sprintf("\n%s%s: %s - %s", mark, episodeCode(episode), episode.Name, episodeAirLabel(&episode, loc, shortDateLayout))
+
+		inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				shorten(fmt.Sprintf("%s%s %s", mark, episodeCode(episode), episode.Name), 40),
+				fmt.Sprintf("%s:%s", ActionEpisode, episode.ID),
+			),
+		))
+	}
+
+	var pager []tgbotapi.InlineKeyboardButton
+
+	if page > 0 {
+		pager = append(pager, tgbotapi.NewInlineKeyboardButtonData("◀️ Prev", seasonCallback(showID, season, page-1)))
+	}
+
+	if page < pages-1 {
+		pager = append(pager, tgbotapi.NewInlineKeyboardButtonData("Next ▶️", seasonCallback(showID, season, page+1)))
+	}
+
+	if len(pager) > 0 {
+		inlineKeyboard = append(inlineKeyboard, pager)
+	}
+
+	inlineKeyboard = append(inlineKeyboard, b.createBackHomeRow(fmt.Sprintf("%s:%s", ActionEpisodes, showID)))
+
+	b.editMessageWithMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...))
+}
+
+// displayEpisode shows an episode's air date and overview with buttons to mark it, its season
+// or everything before it as watched; Back returns to its season page.
+func (b *Bot) displayEpisode(chatID int64, messageID int, episodeID string, userID int) {
+	episode, err := b.dbManager.GetEpisode(episodeID)
+	if err != nil {
+		slog.Error("Error getting episode", "episodeID", episodeID, "err", err)
+		b.showEpisodesError(chatID, messageID)
+
+		return
+	}
+
+	text := fmt.Sprintf("🎬 *%s*\n%s: %s\n\n", episode.Show.Name, episodeCode(*episode), episode.Name)
+	text += fmt.Sprintf("Air date: %s", episodeAirLabel(episode, b.userLocation(int64(userID)), dateLayout))
+
+	if episode.Overview != "" {
+		text += fmt.Sprintf("\n\n%s", strings.TrimSpace(stripHTMLTags(episode.Overview)))
+	}
+
+	watched, err := b.watchedSet(int64(userID), episode.ShowID)
+	if err != nil {
+		slog.Error("Error getting watched episodes", "err", err)
+	}
+
+	watchButton := tgbotapi.NewInlineKeyboardButtonData("✅ Mark watched", fmt.Sprintf("%s:%s", ActionWatch, episode.ID))
+	if watched[episode.ID] {
+		text += "\n\n✅ Watched"
+		watchButton = tgbotapi.NewInlineKeyboardButtonData("↩️ Mark not watched", fmt.Sprintf("%s:%s", ActionUnwatch, episode.ID))
+	}
+
+	page, err := b.seasonPageOf(episode)
+	if err != nil {
+		slog.Error("Error getting episodes", "err", err)
+	}
+
+	b.editMessageWithMenu(
+		chatID,
+		messageID,
+		text,
+		tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(watchButton),
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Mark season watched", fmt.Sprintf("%s:%s", ActionWatchSeason, episode.ID)),
+				tgbotapi.NewInlineKeyboardButtonData("Mark all up to here", fmt.Sprintf("%s:%s", ActionWatchUpTo, episode.ID)),
+			),
+			b.createBackHomeRow(seasonCallback(episode.ShowID, episode.SeasonNumber, page)),
+		),
+	)
+}
+
+// seasonPageOf returns the page of its season's episode list the episode is on.
+func (b *Bot) seasonPageOf(episode *models.Episode) (int, error) {
+	episodes, err := b.dbManager.GetEpisodesForShow(episode.ShowID)
+	if err != nil {
+		return 0, err
+	}
+
+	index := 0
+
+	for _, other := range episodes {
+		if other.ID == episode.ID {
+			break
+		}
+
+		if other.SeasonNumber == episode.SeasonNumber {
+			index++
+		}
+	}
+
+	return index / episodesPerPage, nil
+}
+
+func (b *Bot) showEpisodesError(chatID int64, messageID int) {
+	b.editMessageWithMenu(
+		chatID,
+		messageID,
+		"An error occurred while fetching episodes details.",
+		tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...),
+	)
+}
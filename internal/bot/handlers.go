@@ -1,9 +1,11 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"log/slog"
+	"strconv"
 	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
@@ -11,24 +13,74 @@ import (
 	"github.com/dkhalizov/shows/internal/models"
 )
 
-func (b *Bot) handleCommand(message *tgbotapi.Message) {
-	switch message.Command() {
+// helpText is shown by /help and the Help menu button.
+const helpText = `*TV Shows Notification Bot Help*
+
+• Use the Search button to find shows
+• My Shows displays what you're following
+• Upcoming shows new episodes for your shows
+• You can also just type a show name to search for it
+• /timezone sets the time zone dates are shown in
+• /settings chooses when you're reminded, quiet hours and muted shows
+• /progress shows how far you are in each show; mark episodes watched from the episode list
+• /calendar gives you a private link to subscribe to your episodes in a calendar app
+• /export sends your shows as a file; /import follows the shows in a file from /export, IMDb or Trakt
+• Share on a show's page sends friends a link that opens the show in the bot
+• Add me to a group to follow shows together; group admins choose the shows, and /channel posts reminders to a channel
+
+When you follow a show, you'll receive notifications about new episodes.`
+
+func (b *Bot) handleCommand(ctx context.Context, message *tgbotapi.Message) {
+	command, addressed := b.botCommand(message)
+	if command == "" {
+		return
+	}
+
+	switch command {
 	case "start":
-		b.handleStartCommand(message)
+		b.handleStartCommand(ctx, message)
 	case "help":
 		b.handleHelpCommand(message)
 	case "search":
-		b.handleSearchCommand(message)
+		b.handleSearchCommand(ctx, message)
 	case "list":
 		b.handleListCommand(message)
 	case "upcoming":
 		b.handleUpcomingCommand(message)
+	case "timezone":
+		b.handleTimezoneCommand(message)
+	case "settings":
+		b.handleSettingsCommand(message)
+	case "progress":
+		b.handleProgressCommand(message)
+	case "calendar":
+		b.handleCalendarCommand(message)
+	case "export":
+		b.handleExportCommand(message)
+	case "import":
+		b.handleImportCommand(message)
+	case "channel":
+		b.handleChannelCommand(message)
 	default:
-		b.sendMessage(message.Chat.ID, "Unknown command. Type /help for available commands.")
+		// Unaddressed commands in groups may belong to another bot.
+		if message.Chat.IsPrivate() || addressed {
+			b.sendMessage(message.Chat.ID, "Unknown command. Type /help for available commands.")
+		}
 	}
 }
 
-func (b *Bot) handleStartCommand(message *tgbotapi.Message) {
+func (b *Bot) handleStartCommand(ctx context.Context, message *tgbotapi.Message) {
+	// Users who blocked the bot come back through /start.
+	userID := int64(subscriberID(message.Chat))
+	if err := b.dbManager.SetUserActive(userID, true); err != nil {
+		slog.Error("Error reactivating user", "userID", userID, "err", err)
+	}
+
+	// Links like t.me/<bot>?start=show_<id> arrive as "/start show_<id>".
+	if payload := strings.TrimSpace(message.CommandArguments()); payload != "" && b.handleStartPayload(ctx, message, payload) {
+		return
+	}
+
 	welcomeMsg := `Welcome to the TV Shows Notification Bot!
 
 I'll help you stay updated on your favorite shows. Use the menu below to navigate:
@@ -44,23 +96,14 @@ You can also type a show name directly to search for it.`
 }
 
 func (b *Bot) handleHelpCommand(message *tgbotapi.Message) {
-	helpMsg := `*TV Shows Notification Bot Help*
-
-• Use the Search button to find shows
-• My Shows displays what you'htmlRegexp following
-• Upcoming shows new episodes for your shows
-• You can also just type a show name to search for it
-
-When you follow a show, you'll receive notifications about new episodes.`
-
-	b.sendMessageWithMarkup(message.Chat.ID, helpMsg, b.createMainMenu())
+	b.sendMessageWithMarkup(message.Chat.ID, helpText, b.createMainMenu())
 }
 
-func (b *Bot) handleTextMessage(message *tgbotapi.Message) {
-	b.searchShows(message.Chat.ID, message.Text)
+func (b *Bot) handleTextMessage(ctx context.Context, message *tgbotapi.Message) {
+	b.searchShows(ctx, message.Chat.ID, message.Text)
 }
 
-func (b *Bot) handleSearchCommand(message *tgbotapi.Message) {
+func (b *Bot) handleSearchCommand(ctx context.Context, message *tgbotapi.Message) {
 	query := message.CommandArguments()
 	if query == "" {
 		b.sendMessage(message.Chat.ID, "Please provide a show name to search for. Example: /search Breaking Bad")
@@ -68,11 +111,11 @@ func (b *Bot) handleSearchCommand(message *tgbotapi.Message) {
 		return
 	}
 
-	b.searchShows(message.Chat.ID, query)
+	b.searchShows(ctx, message.Chat.ID, query)
 }
 
 func (b *Bot) handleListCommand(message *tgbotapi.Message) {
-	userID := message.From.ID
+	userID := subscriberID(message.Chat)
 
 	shows, err := b.dbManager.GetUserShows(userID)
 	if err != nil {
@@ -114,7 +157,7 @@ func (b *Bot) handleListCommand(message *tgbotapi.Message) {
 }
 
 func (b *Bot) handleUpcomingCommand(message *tgbotapi.Message) {
-	userID := message.From.ID
+	userID := subscriberID(message.Chat)
 
 	episodes, err := b.dbManager.GetUpcomingEpisodesForUser(userID)
 	if err != nil {
@@ -130,6 +173,7 @@ func (b *Bot) handleUpcomingCommand(message *tgbotapi.Message) {
 		return
 	}
 
+	loc := b.userLocation(int64(userID))
 	msg := "Upcoming episodes for your followed shows:\n"
 
 	episodesByShow := make(map[string][]models.Episode)
@@ -159,7 +203,7 @@ func (b *Bot) handleUpcomingCommand(message *tgbotapi.Message) {
 				episode.SeasonNumber,
 				episode.EpisodeNumber,
 				episode.Name,
-				episode.AirDate.Format("Jan 2, 2006"),
+				formatEpisodeAirTime(&episode, loc, shortDateLayout),
 			)
 		}
 	}
@@ -167,12 +211,13 @@ func (b *Bot) handleUpcomingCommand(message *tgbotapi.Message) {
 	b.sendMessage(message.Chat.ID, msg)
 }
 
-func (b *Bot) handleCallbackQuery(callbackQuery *tgbotapi.CallbackQuery) {
+func (b *Bot) handleCallbackQuery(ctx context.Context, callbackQuery *tgbotapi.CallbackQuery) {
 	data := callbackQuery.Data
-	userID := callbackQuery.From.ID
 	chatID := callbackQuery.Message.Chat.ID
+	// Menus in groups show and change the group's follows, whoever presses the button.
+	userID := subscriberID(callbackQuery.Message.Chat)
 
-	slog.Debug("handleCallbackQuery", "data", data, "userID", userID)
+	slog.Debug("handleCallbackQuery", "data", data, "userID", userID, "fromID", callbackQuery.From.ID)
 
 	switch data {
 	case MenuMain:
@@ -198,6 +243,24 @@ func (b *Bot) handleCallbackQuery(callbackQuery *tgbotapi.CallbackQuery) {
 
 		return
 
+	case MenuSettings:
+		b.displaySettings(chatID, callbackQuery.Message.MessageID, int64(userID))
+		b.answerCallback(callbackQuery.ID, "")
+
+		return
+
+	case MenuProgress:
+		b.displayProgress(chatID, callbackQuery.Message.MessageID, userID)
+		b.answerCallback(callbackQuery.ID, "")
+
+		return
+
+	case MenuMutedShows:
+		b.displayMutedShows(chatID, callbackQuery.Message.MessageID, int64(userID))
+		b.answerCallback(callbackQuery.ID, "")
+
+		return
+
 	case MenuSearch:
 		b.editMessageWithMenu(
 			chatID,
@@ -210,15 +273,6 @@ func (b *Bot) handleCallbackQuery(callbackQuery *tgbotapi.CallbackQuery) {
 		return
 
 	case MenuHelp:
-		helpText := `*TV Shows Notification Bot Help*
-
-• Use the Search button to find shows
-• My Shows displays what you'htmlRegexp following
-• Upcoming shows new episodes for your shows
-• You can also just type a show name to search for it
-
-When you follow a show, you'll receive notifications about new episodes.`
-
 		b.editMessageWithMenu(
 			chatID,
 			callbackQuery.Message.MessageID,
@@ -241,6 +295,12 @@ When you follow a show, you'll receive notifications about new episodes.`
 	action := parts[0]
 	param := parts[1]
 
+	if managedActions[action] && !b.canManage(callbackQuery.Message.Chat, callbackQuery.From) {
+		b.answerCallback(callbackQuery.ID, "Only group admins can change this.")
+
+		return
+	}
+
 	var responseText string
 
 	switch action {
@@ -261,21 +321,24 @@ When you follow a show, you'll receive notifications about new episodes.`
 		} else {
 			responseText = fmt.Sprintf("You are now following %s", show.Name)
 
-			b.displayShowDetails(chatID, callbackQuery.Message.MessageID, param, userID)
+			b.displayShowDetails(chatID, callbackQuery.Message.MessageID, param, userID, BackMyShows)
 		}
 
 		b.answerCallback(callbackQuery.ID, responseText)
 
-		go func() {
-			err = b.storeAllEpisodes(show)
-			if err != nil {
+		// Fetching the full episode list outlives the callback, so it gets its own deadline.
+		b.goTracked(func() {
+			ctx, cancel := withTimeout(context.WithoutCancel(ctx), b.config.Bot.RefreshTimeout, defaultRefreshTimeout)
+			defer cancel()
+
+			if err := b.storeAllEpisodes(ctx, show); err != nil {
 				slog.Error("Error storing episodes", "err", err)
 			}
 
-			if err = b.notifyUsersAboutShowEpisodes(show); err != nil {
+			if err := b.notifyUsersAboutShowEpisodes(ctx, show); err != nil {
 				slog.Error("failed to notify show episodes", "err", err)
 			}
-		}()
+		})
 
 	case ActionUnfollow:
 		show, err := b.dbManager.GetShow(param)
@@ -294,25 +357,129 @@ When you follow a show, you'll receive notifications about new episodes.`
 		} else {
 			responseText = fmt.Sprintf("You have unfollowed %s", show.Name)
 
-			b.displayShowDetails(chatID, callbackQuery.Message.MessageID, param, userID)
+			b.displayShowDetails(chatID, callbackQuery.Message.MessageID, param, userID, BackMyShows)
 		}
 
 		b.answerCallback(callbackQuery.ID, responseText)
 
 	case ActionDetails:
-		b.displayShowDetails(chatID, callbackQuery.Message.MessageID, param, userID)
+		b.displayShowDetails(chatID, callbackQuery.Message.MessageID, param, userID, BackMyShows)
+		b.answerCallback(callbackQuery.ID, "")
+
+	case ActionResult:
+		b.displayShowDetails(chatID, callbackQuery.Message.MessageID, param, userID, BackSearchResults)
+		b.answerCallback(callbackQuery.ID, "")
+
+	case ActionPage:
+		page, err := strconv.Atoi(param)
+		if err != nil {
+			b.answerCallback(callbackQuery.ID, "Invalid action")
+
+			return
+		}
+
+		b.displaySearchResults(chatID, callbackQuery.Message.MessageID, page)
 		b.answerCallback(callbackQuery.ID, "")
 
 	case ActionEpisodes:
 		b.displayShowEpisodes(chatID, callbackQuery.Message.MessageID, param)
 		b.answerCallback(callbackQuery.ID, "")
+
+	case ActionSeason:
+		showID, season, page, err := parseSeasonParam(param)
+		if err != nil {
+			slog.Error("Invalid season callback", "data", data, "err", err)
+			b.answerCallback(callbackQuery.ID, "Invalid action")
+
+			return
+		}
+
+		b.displaySeason(chatID, callbackQuery.Message.MessageID, showID, season, page, userID)
+		b.answerCallback(callbackQuery.ID, "")
+
+	case ActionEpisode:
+		b.displayEpisode(chatID, callbackQuery.Message.MessageID, param, userID)
+		b.answerCallback(callbackQuery.ID, "")
+
+	case ActionWatch, ActionUnwatch, ActionWatchSeason, ActionWatchUpTo:
+		count, err := b.markWatched(int64(userID), param, action)
+		if err != nil {
+			slog.Error("Error marking episodes watched", "episodeID", param, "err", err)
+			b.answerCallback(callbackQuery.ID, "An error occurred while saving your progress.")
+
+			return
+		}
+
+		b.displayEpisode(chatID, callbackQuery.Message.MessageID, param, userID)
+		b.answerCallback(callbackQuery.ID, watchedMessage(action, count))
+
+	case ActionCalendar:
+		if err := b.displayCalendar(chatID, callbackQuery.Message.MessageID, int64(userID), param == "rotate"); err != nil {
+			slog.Error("Error rotating calendar link", "err", err)
+			b.answerCallback(callbackQuery.ID, "An error occurred while creating a new link.")
+
+			return
+		}
+
+		b.answerCallback(callbackQuery.ID, "New link created, the old one no longer works")
+
+	case ActionLead:
+		if err := b.toggleLeadTime(int64(userID), param); err != nil {
+			slog.Error("Error updating lead times", "err", err)
+			b.answerCallback(callbackQuery.ID, "An error occurred while saving your settings.")
+
+			return
+		}
+
+		b.displaySettings(chatID, callbackQuery.Message.MessageID, int64(userID))
+		b.answerCallback(callbackQuery.ID, "")
+
+	case ActionQuiet:
+		if err := b.cycleQuietHours(int64(userID)); err != nil {
+			slog.Error("Error updating quiet hours", "err", err)
+			b.answerCallback(callbackQuery.ID, "An error occurred while saving your settings.")
+
+			return
+		}
+
+		b.displaySettings(chatID, callbackQuery.Message.MessageID, int64(userID))
+		b.answerCallback(callbackQuery.ID, "")
+
+	case ActionDigest:
+		if err := b.cycleDigest(int64(userID), param); err != nil {
+			slog.Error("Error updating digest settings", "err", err)
+			b.answerCallback(callbackQuery.ID, "An error occurred while saving your settings.")
+
+			return
+		}
+
+		b.displaySettings(chatID, callbackQuery.Message.MessageID, int64(userID))
+		b.answerCallback(callbackQuery.ID, "")
+
+	case ActionMute:
+		muted, err := b.toggleShowMuted(int64(userID), param)
+		if err != nil {
+			slog.Error("Error muting show", "err", err)
+			b.answerCallback(callbackQuery.ID, "You can only mute shows you follow.")
+
+			return
+		}
+
+		responseText = "Reminders back on"
+		if muted {
+			responseText = "Show muted"
+		}
+
+		b.displayMutedShows(chatID, callbackQuery.Message.MessageID, int64(userID))
+		b.answerCallback(callbackQuery.ID, responseText)
+
 	case ActionBack:
 		switch param {
-		case "search_results":
-			responseText = "Returning to search results..."
-			b.answerCallback(callbackQuery.ID, responseText)
+		case BackSearchResults:
+			b.displayLastSearchResults(chatID, callbackQuery.Message.MessageID)
+			b.answerCallback(callbackQuery.ID, "")
 
-		case "my_shows":
+		case BackMyShows:
 			b.displayUserShows(chatID, callbackQuery.Message.MessageID, userID)
 			b.answerCallback(callbackQuery.ID, "")
 
@@ -332,25 +499,24 @@ When you follow a show, you'll receive notifications about new episodes.`
 	}
 }
 
-func (b *Bot) storeAllEpisodes(show *models.Show) error {
-	episodes, err := b.apiClients[show.Provider].GetEpisodes(show.ProviderID)
+func (b *Bot) storeAllEpisodes(ctx context.Context, show *models.Show) error {
+	client, ok := b.apiClients[show.Provider]
+	if !ok {
+		return fmt.Errorf("apiClient for show %s : %s not found", show.ID, show.Provider)
+	}
+
+	episodes, err := client.GetEpisodes(ctx, show.ProviderID)
 	if err != nil {
 		return fmt.Errorf("error getting episodes %w", err)
 	}
 
-	for _, episode := range episodes {
-		episode.Show = *show
-		_, err = b.dbManager.StoreEpisode(&episode)
-		if err != nil {
-			return fmt.Errorf("error storing episode %w", err)
-		}
-	}
+	b.storeEpisodes(ctx, show, episodes)
 
 	return nil
 }
 
 func (b *Bot) answerCallback(id, text string) {
-	if _, err := b.api.AnswerCallbackQuery(tgbotapi.NewCallback(id, text)); err != nil {
+	if err := b.api.AnswerCallback(tgbotapi.NewCallback(id, text)); err != nil {
 		slog.Error("Error answering callback query", "err", err)
 	}
 }
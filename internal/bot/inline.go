@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+const (
+	inlineResultsPerPage = 20
+
+	// inlineCacheTime is how many seconds Telegram may reuse an answer for the same query.
+	inlineCacheTime = 300
+)
+
+// handleInlineQuery answers "@bot <title>" with one article per matching show, paged through the query offset.
+func (b *Bot) handleInlineQuery(ctx context.Context, query *tgbotapi.InlineQuery) {
+	answer := tgbotapi.InlineConfig{
+		InlineQueryID:     query.ID,
+		CacheTime:         inlineCacheTime,
+		SwitchPMText:      "Open the bot to follow shows",
+		SwitchPMParameter: startInline,
+		Results:           []any{},
+	}
+
+	if text := strings.TrimSpace(query.Query); text != "" {
+		shows := b.findShows(ctx, text)
+
+		offset, _ := strconv.Atoi(query.Offset)
+		offset = max(0, min(offset, len(shows)))
+		end := min(offset+inlineResultsPerPage, len(shows))
+
+		for _, show := range shows[offset:end] {
+			answer.Results = append(answer.Results, b.inlineShowResult(show))
+		}
+
+		if end < len(shows) {
+			answer.NextOffset = strconv.Itoa(end)
+		}
+	}
+
+	if err := b.api.AnswerInlineQuery(answer); err != nil {
+		slog.Error("Error answering inline query", "err", err)
+	}
+}
+
+// handleChosenInlineResult records which show was shared; Telegram only reports these
+// when inline feedback is enabled with BotFather.
+func (b *Bot) handleChosenInlineResult(result *tgbotapi.ChosenInlineResult) {
+	slog.Info("Inline result chosen", "showID", result.ResultID, "userID", result.From.ID, "query", result.Query)
+}
+
+// inlineShowResult is the article for a show: a card with its overview and a button to follow it in the bot.
+func (b *Bot) inlineShowResult(show models.Show) tgbotapi.InlineQueryResultArticle {
+	title := show.Name
+	if !show.FirstAirDate.IsZero() {
+		title += fmt.Sprintf(" (%d)", show.FirstAirDate.Year())
+	}
+
+	article := tgbotapi.NewInlineQueryResultArticle(show.ID, title, "")
+	article.InputMessageContent = tgbotapi.InputTextMessageContent{
+		Text:      escapeMarkdown(truncateForTelegram(showCard(show))),
+		ParseMode: "MarkdownV2",
+	}
+	article.ThumbURL = show.PosterURL
+	article.Description = show.Status
+
+	if overview := strings.TrimSpace(stripHTMLTags(show.Overview)); overview != "" {
+		article.Description = shorten(overview, 100)
+	}
+
+	// Shows without a valid start payload get no button rather than a broken link.
+	if payload := showStartPayload(&show); payload != "" {
+		if link := b.startLink(payload); link != "" {
+			markup := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonURL("➕ Follow in bot", link),
+			))
+			article.ReplyMarkup = &markup
+		}
+	}
+
+	return article
+}
+
+// showCard describes a show for people who may not use the bot.
+func showCard(show models.Show) string {
+	text := fmt.Sprintf("🎬 %s\n", show.Name)
+
+	if overview := strings.TrimSpace(stripHTMLTags(show.Overview)); overview != "" {
+		text += fmt.Sprintf("\n%s\n", shorten(overview, 300))
+	}
+
+	if show.Status != "" {
+		text += fmt.Sprintf("\nStatus: %s", show.Status)
+	}
+
+	if !show.FirstAirDate.IsZero() {
+		text += fmt.Sprintf("\nFirst aired: %s", show.FirstAirDate.Format("January 2, 2006"))
+	}
+
+	return text
+}
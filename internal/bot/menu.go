@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 
@@ -17,11 +20,36 @@ const (
 	MenuSearch   = "menu_search"
 	MenuHelp     = "menu_help"
 
+	MenuSettings   = "menu_settings"
+	MenuMutedShows = "menu_muted_shows"
+	MenuProgress   = "menu_progress"
+
 	ActionFollow   = "follow"
 	ActionUnfollow = "unfollow"
 	ActionDetails  = "details"
 	ActionEpisodes = "episodes"
 	ActionBack     = "back"
+	ActionLead     = "lead"
+	ActionQuiet    = "quiet"
+	ActionMute     = "mute"
+	ActionDigest   = "digest"
+	ActionPage     = "page"   // search results page
+	ActionResult   = "result" // show details opened from search results
+	ActionSeason   = "season"
+	ActionEpisode  = "episode"
+
+	ActionWatch       = "watch"
+	ActionUnwatch     = "unwatch"
+	ActionWatchSeason = "watchseason"
+	ActionWatchUpTo   = "watchupto"
+
+	ActionCalendar = "calendar"
+)
+
+// Back targets of ActionBack.
+const (
+	BackSearchResults = "search_results"
+	BackMyShows       = "my_shows"
 )
 
 func (b *Bot) createMainMenu() tgbotapi.InlineKeyboardMarkup {
@@ -34,6 +62,10 @@ func (b *Bot) createMainMenu() tgbotapi.InlineKeyboardMarkup {
 			tgbotapi.NewInlineKeyboardButtonData("📅 Upcoming", MenuUpcoming),
 			tgbotapi.NewInlineKeyboardButtonData("❓ Help", MenuHelp),
 		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("📊 Progress", MenuProgress),
+			tgbotapi.NewInlineKeyboardButtonData("⚙️ Settings", MenuSettings),
+		),
 	)
 }
 
@@ -59,21 +91,73 @@ func (b *Bot) editMessageWithMenu(chatID int64, messageID int, text string, mark
 	msg.ParseMode = "MarkdownV2"
 	msg.ReplyMarkup = &markup
 
-	_, err := b.api.Send(msg)
+	_, err := b.sender.sendNow(chatID, msg, laneInteractive)
 	if err != nil {
 		slog.Error("failed to edit message", "err", err)
 	}
 }
 
+// telegramMessageLimit is the maximum message length, counted by Telegram in UTF-16 code units.
+const telegramMessageLimit = 4096
+
+// truncateForTelegram keeps the beginning of text that fits in one message once escaped for
+// MarkdownV2. It cuts at a line break where it can, and never inside a character or an escape.
 func truncateForTelegram(text string) string {
-	const maxLength = 4000
+	const ellipsis = "\n…"
+
+	if escapedLength(text) <= telegramMessageLimit {
+		return text
+	}
 
-	if len(text) > maxLength {
-		// Show only the last part of the text
-		text = "...\n" + text[len(text)-maxLength:]
+	budget := telegramMessageLimit - escapedLength(ellipsis)
+	used, cut, lineEnd := 0, 0, 0
+
+	for i, r := range text {
+		size := escapedLength(string(r))
+		if used+size > budget {
+			break
+		}
+
+		used += size
+		cut = i + utf8.RuneLen(r)
+
+		if r == '\n' {
+			lineEnd = i
+		}
 	}
 
-	return text
+	// Dropping a partial last line is better than ending mid-word, unless it costs too much text.
+	if lineEnd > cut/2 {
+		cut = lineEnd
+	}
+
+	return text[:cut] + ellipsis
+}
+
+// escapedLength returns the length Telegram counts for text after escapeMarkdown.
+func escapedLength(text string) int {
+	length := 0
+
+	for _, r := range text {
+		length += utf16.RuneLen(r)
+
+		if strings.ContainsRune(markdownSpecialChars, r) {
+			length++
+		}
+	}
+
+	return length
+}
+
+// shorten limits s to maxRunes characters, marking a cut with "...".
+func shorten(s string, maxRunes int) string {
+	if utf8.RuneCountInString(s) <= maxRunes {
+		return s
+	}
+
+	runes := []rune(s)
+
+	return string(runes[:maxRunes-3]) + "..."
 }
 
 func (b *Bot) displayUserShows(chatID int64, messageID, userID int) {
@@ -125,7 +209,8 @@ func (b *Bot) displayUserShows(chatID int64, messageID, userID int) {
 	)
 }
 
-func (b *Bot) displayShowDetails(chatID int64, messageID int, showID string, userID int) {
+// displayShowDetails shows a show in place of messageID; its Back button returns to backTarget.
+func (b *Bot) displayShowDetails(chatID int64, messageID int, showID string, userID int, backTarget string) {
 	show, err := b.dbManager.GetShow(showID)
 	if err != nil {
 		b.editMessageWithMenu(
@@ -138,6 +223,12 @@ func (b *Bot) displayShowDetails(chatID int64, messageID int, showID string, use
 		return
 	}
 
+	details, markup := b.showDetails(show, userID, backTarget)
+	b.editMessageWithMenu(chatID, messageID, details, markup)
+}
+
+// showDetails returns the details card of a show and its buttons for userID.
+func (b *Bot) showDetails(show *models.Show, userID int, backTarget string) (string, tgbotapi.InlineKeyboardMarkup) {
 	following, err := b.dbManager.IsUserFollowingShow(userID, show.ID)
 	if err != nil {
 		log.Printf("Error checking if user is following show: %v", err)
@@ -151,12 +242,7 @@ func (b *Bot) displayShowDetails(chatID int64, messageID int, showID string, use
 	details := fmt.Sprintf("🎬 *%s*\n\n", show.Name)
 
 	if show.Overview != "" {
-		overview := show.Overview
-		if len(overview) > 150 {
-			overview = overview[:147] + "..."
-		}
-
-		details += fmt.Sprintf("%s\n\n", overview)
+		details += fmt.Sprintf("%s\n\n", shorten(show.Overview, 150))
 	}
 
 	details += fmt.Sprintf("Status: %s\n", show.Status)
@@ -168,7 +254,8 @@ func (b *Bot) displayShowDetails(chatID int64, messageID int, showID string, use
 			nextEpisode.EpisodeNumber,
 			nextEpisode.Name,
 		)
-		details += fmt.Sprintf("Air date: %s", nextEpisode.AirDate.Format("January 2, 2006"))
+		details += fmt.Sprintf("Air date: %s",
+			formatEpisodeAirTime(nextEpisode, b.userLocation(int64(userID)), "January 2, 2006"))
 	}
 
 	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
@@ -186,17 +273,18 @@ func (b *Bot) displayShowDetails(chatID int64, messageID int, showID string, use
 		))
 	}
 
+	if link := b.shareLink(show); link != "" {
+		inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonURL("📤 Share", link),
+		))
+	}
+
 	inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
-		tgbotapi.NewInlineKeyboardButtonData("◀️ Back", fmt.Sprintf("%s:my_shows", ActionBack)),
+		tgbotapi.NewInlineKeyboardButtonData("◀️ Back", fmt.Sprintf("%s:%s", ActionBack, backTarget)),
 		tgbotapi.NewInlineKeyboardButtonData("🏠 Home", MenuMain),
 	))
 
-	b.editMessageWithMenu(
-		chatID,
-		messageID,
-		details,
-		tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...),
-	)
+	return details, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...)
 }
 
 func (b *Bot) displayUpcomingEpisodes(chatID int64, messageID, userID int) {
@@ -223,6 +311,7 @@ func (b *Bot) displayUpcomingEpisodes(chatID int64, messageID, userID int) {
 		return
 	}
 
+	loc := b.userLocation(int64(userID))
 	text := "📅 *Upcoming Episodes*\n"
 
 	episodesByShow := make(map[string][]models.Episode)
@@ -254,7 +343,7 @@ func (b *Bot) displayUpcomingEpisodes(chatID int64, messageID, userID int) {
 				episode.SeasonNumber,
 				episode.EpisodeNumber,
 				episode.Name,
-				episode.AirDate.Format("Jan 2, 2006"),
+				formatEpisodeAirTime(&episode, loc, shortDateLayout),
 			)
 		}
 	}
@@ -262,10 +351,7 @@ func (b *Bot) displayUpcomingEpisodes(chatID int64, messageID, userID int) {
 	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
 
 	for showName, showID := range showIDs {
-		shortName := showName
-		if len(shortName) > 20 {
-			shortName = shortName[:17] + "..."
-		}
+		shortName := shorten(showName, 20)
 
 		inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
 			tgbotapi.NewInlineKeyboardButtonData(
@@ -286,42 +372,3 @@ func (b *Bot) displayUpcomingEpisodes(chatID int64, messageID, userID int) {
 		tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...),
 	)
 }
-
-func (b *Bot) displayShowEpisodes(chatID int64, messageID int, showID string) {
-	episodes, err := b.dbManager.GetEpisodesForShow(showID)
-	if err != nil {
-		slog.Error("Error getting episodes", "err", err)
-		b.editMessageWithMenu(
-			chatID,
-			messageID,
-			"An error occurred while fetching episodes details.",
-			tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...),
-		)
-
-		return
-	}
-
-	if len(episodes) == 0 {
-		b.editMessageWithMenu(
-			chatID,
-			messageID,
-			"No episodes found for this show.",
-			tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...),
-		)
-
-		return
-	}
-
-	text := fmt.Sprintf("📋 *All Episodes*\n\n*%s*\n\n", episodes[0].Show.Name)
-
-	for _, episode := range episodes {
-		text += fmt.Sprintf("S%02dE%02d: %s - %s\n",
-			episode.SeasonNumber,
-			episode.EpisodeNumber,
-			episode.Name,
-			episode.AirDate.Format("Jan 2, 2006"),
-		)
-	}
-
-	b.editMessageWithMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...))
-}
@@ -0,0 +1,138 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+// Messenger is the part of the Telegram Bot API the bot talks to.
+type Messenger interface {
+	Send(c tgbotapi.Chattable) (tgbotapi.Message, error)
+	AnswerCallback(config tgbotapi.CallbackConfig) error
+	AnswerInlineQuery(config tgbotapi.InlineConfig) error
+	GetUpdates(config tgbotapi.UpdateConfig) ([]tgbotapi.Update, error)
+	GetChat(config tgbotapi.ChatConfig) (tgbotapi.Chat, error)
+	GetChatMember(config tgbotapi.ChatConfigWithUser) (tgbotapi.ChatMember, error)
+	DownloadFile(ctx context.Context, fileID string, maxSize int64) ([]byte, error)
+
+	// Username is the bot's @username, used in t.me links.
+	Username() string
+}
+
+// errFileTooLarge is returned by DownloadFile for files over the requested size.
+var errFileTooLarge = errors.New("file too large")
+
+// webhookRegistrar is implemented by messengers that can point Telegram at a webhook.
+type webhookRegistrar interface {
+	SetWebhook(webhookURL, secret string) error
+}
+
+type botAPIMessenger struct {
+	api *tgbotapi.BotAPI
+}
+
+// NewMessenger connects to the Bot API with token over client.
+func NewMessenger(token string, client *http.Client, debug bool) (Messenger, error) {
+	api, err := tgbotapi.NewBotAPIWithClient(token, client)
+	if err != nil {
+		return nil, err
+	}
+
+	api.Debug = debug
+
+	return &botAPIMessenger{api: api}, nil
+}
+
+func (m *botAPIMessenger) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	return m.api.Send(c)
+}
+
+func (m *botAPIMessenger) AnswerCallback(config tgbotapi.CallbackConfig) error {
+	_, err := m.api.AnswerCallbackQuery(config)
+
+	return err
+}
+
+func (m *botAPIMessenger) AnswerInlineQuery(config tgbotapi.InlineConfig) error {
+	_, err := m.api.AnswerInlineQuery(config)
+
+	return err
+}
+
+func (m *botAPIMessenger) Username() string {
+	return m.api.Self.UserName
+}
+
+func (m *botAPIMessenger) GetUpdates(config tgbotapi.UpdateConfig) ([]tgbotapi.Update, error) {
+	return m.api.GetUpdates(config)
+}
+
+func (m *botAPIMessenger) GetChat(config tgbotapi.ChatConfig) (tgbotapi.Chat, error) {
+	return m.api.GetChat(config)
+}
+
+func (m *botAPIMessenger) GetChatMember(config tgbotapi.ChatConfigWithUser) (tgbotapi.ChatMember, error) {
+	return m.api.GetChatMember(config)
+}
+
+// DownloadFile fetches a file a user sent to the bot.
+func (m *botAPIMessenger) DownloadFile(ctx context.Context, fileID string, maxSize int64) ([]byte, error) {
+	fileURL, err := m.api.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("getFile: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := m.api.Client.Do(req)
+	if err != nil {
+		// The file URL contains the bot token, so it is kept out of the error.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download file: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("download file: %w", err)
+	}
+
+	if int64(len(data)) > maxSize {
+		return nil, errFileTooLarge
+	}
+
+	return data, nil
+}
+
+// SetWebhook registers webhookURL; tgbotapi's own SetWebhook cannot send a secret token.
+func (m *botAPIMessenger) SetWebhook(webhookURL, secret string) error {
+	params := url.Values{}
+	params.Set("url", webhookURL)
+
+	if secret != "" {
+		params.Set("secret_token", secret)
+	}
+
+	if _, err := m.api.MakeRequest("setWebhook", params); err != nil {
+		return fmt.Errorf("setWebhook: %w", err)
+	}
+
+	return nil
+}
@@ -1,22 +1,79 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/dkhalizov/shows/internal/models"
 )
 
-func (b *Bot) runNotificationChecker() {
-	b.checkForNewEpisodes()
+// runNotificationChecker runs a check immediately and then on every tick until ctx is cancelled.
+// Between provider refreshes, stored episodes are re-checked every reminder interval so short
+// lead times such as "1 hour before" and scheduled digests go out on time. Each run uses
+// handlerCtx, so a run under way when ctx is cancelled drains like an update handler.
+func (b *Bot) runNotificationChecker(ctx, handlerCtx context.Context) {
+	defer b.notifyTicker.Stop()
+
+	reminderTicker := time.NewTicker(b.reminderInterval())
+	defer reminderTicker.Stop()
+
+	b.checkForNewEpisodes(handlerCtx)
+	b.sendDueDigests(handlerCtx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug("Notification checker stopped")
+
+			return
+		case <-b.notifyTicker.C:
+			b.checkForNewEpisodes(handlerCtx)
+		case <-reminderTicker.C:
+			b.sendDueReminders(handlerCtx)
+			b.sendDueDigests(handlerCtx)
+		}
+	}
+}
 
-	for range b.notifyTicker.C {
-		b.checkForNewEpisodes()
+func (b *Bot) reminderInterval() time.Duration {
+	if b.config.Bot.ReminderInterval > 0 {
+		return b.config.Bot.ReminderInterval
 	}
+
+	return defaultReminderInterval
 }
 
-func (b *Bot) checkForNewEpisodes() {
+// sendDueReminders notifies followers about stored episodes without refreshing them from providers.
+func (b *Bot) sendDueReminders(ctx context.Context) {
+	showIDs, err := b.dbManager.GetAllFollowedShows()
+	if err != nil {
+		slog.Error("Error querying followed shows", "err", err)
+
+		return
+	}
+
+	for _, showID := range showIDs {
+		if ctx.Err() != nil {
+			return
+		}
+
+		show, err := b.dbManager.GetShow(showID)
+		if err != nil {
+			slog.Error("Error querying show", "showID", showID, "err", err)
+
+			continue
+		}
+
+		if err = b.notifyUsersAboutShowEpisodes(ctx, show); err != nil {
+			slog.Error("Error notifying users about the show", "showID", showID, "err", err)
+		}
+	}
+}
+
+func (b *Bot) checkForNewEpisodes(ctx context.Context) {
 	showIDs, err := b.dbManager.GetAllFollowedShows()
 	if err != nil {
 		slog.Error("Error querying followed shows", "err", err)
@@ -27,6 +84,12 @@ func (b *Bot) checkForNewEpisodes() {
 	slog.Debug("Checking for new episodes...", "showIDs", showIDs)
 
 	for _, showID := range showIDs {
+		if ctx.Err() != nil {
+			slog.Info("Notification run interrupted by shutdown")
+
+			return
+		}
+
 		show, err := b.dbManager.GetShow(showID)
 		if err != nil {
 			slog.Error("Error querying show", "showID", showID, "err", err)
@@ -34,26 +97,137 @@ func (b *Bot) checkForNewEpisodes() {
 			continue
 		}
 
-		if err = b.refreshShowEpisodes(show); err != nil {
+		refreshCtx, cancel := withTimeout(ctx, b.config.Bot.RefreshTimeout, defaultRefreshTimeout)
+		if b.showDetailsStale(show) {
+			if err = b.refreshShowDetails(refreshCtx, show); err != nil {
+				slog.Error("Error refreshing show details", "showID", showID, "err", err)
+			}
+		}
+
+		if err = b.refreshShowEpisodes(refreshCtx, show); err != nil {
 			slog.Error("Error refreshing episodes for show", "showID", showID, "err", err)
 		}
 
-		if err = b.notifyUsersAboutShowEpisodes(show); err != nil {
+		cancel()
+
+		if err = b.notifyUsersAboutShowEpisodes(ctx, show); err != nil {
 			slog.Error("Error notifying users about the show", "showID", showID, "err", err)
 		}
 	}
 }
 
+func (b *Bot) showDetailsStale(show *models.Show) bool {
+	interval := b.config.Bot.ShowRefreshInterval
+	if interval <= 0 {
+		interval = defaultShowRefreshInterval
+	}
+
+	return time.Since(show.RefreshedAt) >= interval
+}
+
+// refreshShowDetails re-fetches show metadata from the provider, stores it and
+// tells followers when the show's status changed, e.g. it ended or was renewed.
+func (b *Bot) refreshShowDetails(ctx context.Context, show *models.Show) error {
+	client, ok := b.apiClients[show.Provider]
+	if !ok {
+		return fmt.Errorf("apiClient for show %s : %s not found", show.ID, show.Provider)
+	}
+
+	details, err := client.GetShowDetails(ctx, show.ProviderID)
+	if err != nil {
+		return fmt.Errorf("could not get show details: %w", err)
+	}
+
+	details.ID = show.ID
+
+	oldStatus, err := b.dbManager.UpdateShowDetails(details)
+	if err != nil {
+		return err
+	}
+
+	updated, err := b.dbManager.GetShow(show.ID)
+	if err != nil {
+		return err
+	}
+
+	*show = *updated
+
+	if oldStatus == "" || oldStatus == show.Status {
+		return nil
+	}
+
+	slog.Info("Show status changed", "showName", show.Name, "oldStatus", oldStatus, "newStatus", show.Status)
+
+	return b.notifyUsersAboutStatusChange(ctx, show, oldStatus)
+}
+
+func (b *Bot) notifyUsersAboutStatusChange(ctx context.Context, show *models.Show, oldStatus string) error {
+	message := statusChangeMessage(show, oldStatus)
+	if message == "" {
+		return nil
+	}
+
+	userIDs, err := b.dbManager.GetShowFollowers(show.ID)
+	if err != nil {
+		return fmt.Errorf("could not get show followers: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Plain text, so it can go to a linked channel as is.
+		msg, err := newOutboxMessage(b.notificationChat(userID), message, nil)
+		if err != nil {
+			return err
+		}
+
+		if err = b.dbManager.EnqueueMessage(msg); err != nil {
+			slog.Error("Error queueing status change alert", "userID", userID, "err", err)
+		}
+	}
+
+	return nil
+}
+
+// statusChangeMessage describes a status transition using the wording of both providers
+// (TVMaze: Running, Ended, To Be Determined, In Development; TMDB: Returning Series,
+// Ended, Canceled, In Production, Planned, Pilot). Returns "" for transitions not worth an alert.
+func statusChangeMessage(show *models.Show, oldStatus string) string {
+	var headline string
+
+	switch strings.ToLower(show.Status) {
+	case "ended":
+		headline = "🏁 *Series Ended* 🏁"
+	case "canceled", "cancelled":
+		headline = "❌ *Series Cancelled* ❌"
+	case "running", "returning series", "in production":
+		switch strings.ToLower(oldStatus) {
+		case "ended", "canceled", "cancelled", "to be determined":
+			headline = "🎉 *Series Renewed* 🎉"
+		default:
+			headline = "📺 *Series Returning* 📺"
+		}
+	case "to be determined":
+		headline = "⏳ *Series Future Uncertain* ⏳"
+	default:
+		return ""
+	}
+
+	return fmt.Sprintf("%s\n\n*%s*\n\nStatus changed from %s to %s.", headline, show.Name, oldStatus, show.Status)
+}
+
 // refreshShowEpisodes fetches only upcoming episode data from the API and updates the database
 // This is separated from notification logic to ensure we always have updated episode data
-func (b *Bot) refreshShowEpisodes(show *models.Show) error {
+func (b *Bot) refreshShowEpisodes(ctx context.Context, show *models.Show) error {
 	client, ok := b.apiClients[show.Provider]
 	if !ok {
 		return fmt.Errorf("apiClient for show %s : %s not found", show.ID, show.Provider)
 	}
 
 	// Only get upcoming episodes from the API
-	episodes, err := client.GetUpcomingEpisodes(show.ProviderID)
+	episodes, err := client.GetUpcomingEpisodes(ctx, show.ProviderID)
 	if err != nil {
 		slog.Error("Failed to get upcoming episodes, falling back to stored episodes",
 			"showID", show.ID,
@@ -72,25 +246,133 @@ func (b *Bot) refreshShowEpisodes(show *models.Show) error {
 		"count", len(episodes))
 
 	// Store only upcoming episodes
+	b.storeEpisodes(ctx, show, episodes)
+
+	return nil
+}
+
+// storeEpisodes upserts provider episodes for show and alerts followers when a stored episode moved.
+func (b *Bot) storeEpisodes(ctx context.Context, show *models.Show, episodes []models.Episode) {
 	for _, episode := range episodes {
 		episode.ShowID = show.ID
-		_, err := b.dbManager.StoreEpisode(&episode)
+
+		change, err := b.dbManager.StoreEpisode(&episode)
 		if err != nil {
 			slog.Error("Error storing episode", "episode", episode.Name, "err", err)
+
+			continue
+		}
+
+		if change == nil {
+			continue
+		}
+
+		slog.Info("Episode changed",
+			"showName", show.Name,
+			"episodeID", episode.ID,
+			"oldName", change.OldName,
+			"newName", change.NewName,
+			"oldAirDate", change.OldAirDate,
+			"newAirDate", change.NewAirDate)
+
+		if change.Rescheduled() {
+			if err = b.notifyUsersAboutReschedule(ctx, show, &episode, change); err != nil {
+				slog.Error("Error notifying users about rescheduled episode", "episodeID", episode.ID, "err", err)
+			}
+		}
+	}
+}
+
+// notifyUsersAboutReschedule tells followers that a known air date moved, provided either
+// date is within rescheduleAlertWindow, and re-arms the reminders that fall due again for the new date.
+func (b *Bot) notifyUsersAboutReschedule(
+	ctx context.Context,
+	show *models.Show,
+	episode *models.Episode,
+	change *models.EpisodeChange,
+) error {
+	now := time.Now()
+	inWindow := func(t time.Time) bool {
+		return !t.IsZero() && t.After(now) && t.Before(now.Add(rescheduleAlertWindow))
+	}
+
+	if change.OldAirDate.IsZero() || !(inWindow(change.OldAirDate) || inWindow(change.NewAirDate)) {
+		return nil
+	}
+
+	if err := b.dbManager.ClearNotifications(episode.ID, b.rearmedKinds(change.NewAirDate, now)); err != nil {
+		return fmt.Errorf("could not clear notifications: %w", err)
+	}
+
+	userIDs, err := b.dbManager.GetShowFollowers(show.ID)
+	if err != nil {
+		return fmt.Errorf("could not get show followers: %w", err)
+	}
+
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		loc := b.userLocation(userID)
+		was := formatAirTime(change.OldAirDate, change.OldHasAirTime, loc, dateLayout)
+
+		newTime := "to be announced"
+		if !change.NewAirDate.IsZero() {
+			newTime = formatEpisodeAirTime(episode, loc, dateLayout)
+		}
+
+		message := fmt.Sprintf("📅 *Episode Rescheduled* 📅\n\n*%s*\nSeason %d, Episode %d: %s\n\nWas: %s\nNow: %s",
+			show.Name,
+			episode.SeasonNumber,
+			episode.EpisodeNumber,
+			episode.Name,
+			was,
+			newTime,
+		)
+
+		msg, err := newOutboxMessage(b.notificationChat(userID), message, nil)
+		if err != nil {
+			return err
+		}
+
+		if err = b.dbManager.EnqueueMessage(msg); err != nil {
+			slog.Error("Error queueing reschedule alert", "userID", userID, "err", err)
 		}
 	}
 
 	return nil
 }
 
-func (b *Bot) notifyUsersAboutShowEpisodes(show *models.Show) error {
+// rearmedKinds returns the reminder kinds to send again for an episode moved to newAirTime: those
+// due after now, so a reminder already sent is not repeated at once. While the new date is unknown
+// every kind is re-armed. Digests are never repeated.
+func (b *Bot) rearmedKinds(newAirTime, now time.Time) []string {
+	leads := append([]models.LeadTime{{Kind: models.KindDefault, Before: b.config.Bot.EpisodeNotificationThreshold}},
+		models.LeadTimes...)
+
+	var kinds []string
+
+	for _, lead := range leads {
+		if newAirTime.IsZero() || newAirTime.Add(-lead.Before).After(now) {
+			kinds = append(kinds, lead.Kind)
+		}
+	}
+
+	return kinds
+}
+
+func (b *Bot) notifyUsersAboutShowEpisodes(ctx context.Context, show *models.Show) error {
 	episodes, err := b.dbManager.GetEpisodesForShow(show.ID)
 	if err != nil {
 		return fmt.Errorf("could not get episodes for show %s : %w", show.ID, err)
 	}
 
 	now := time.Now()
-	notificationThreshold := now.Add(b.config.Bot.EpisodeNotificationThreshold)
+
+	// The longest lead anyone can pick bounds which episodes may have a reminder due;
+	// GetUsersToNotify applies each user's own lead times, quiet hours and mutes.
+	horizon := max(b.config.Bot.EpisodeNotificationThreshold, models.LeadTimes[0].Before)
 
 	slog.Debug("Checking episodes for notification",
 		"show", show.Name,
@@ -98,15 +380,13 @@ func (b *Bot) notifyUsersAboutShowEpisodes(show *models.Show) error {
 		"threshold", b.config.Bot.EpisodeNotificationThreshold)
 
 	for _, episode := range episodes {
-		// Only notify for episodes that:
-		// 1. Are in the future
-		// 2. Are within the notification threshold (e.g., in the next 24 hours)
-		// 3. Haven't been notified yet
-		isFutureEpisode := episode.AirDate.After(now)
-		isWithinThreshold := episode.AirDate.Before(notificationThreshold)
-
-		if isFutureEpisode && isWithinThreshold {
-			if err = b.notifyUsersAboutEpisode(show, &episode); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		airTime := episode.AirTime()
+		if airTime.After(now.Add(-models.OnAirWindow)) && airTime.Before(now.Add(horizon)) {
+			if err = b.notifyUsersAboutEpisode(ctx, show, &episode); err != nil {
 				slog.Error("Error notifying users about episode",
 					"episodeID", episode.ID,
 					"showName", show.Name,
@@ -119,13 +399,13 @@ func (b *Bot) notifyUsersAboutShowEpisodes(show *models.Show) error {
 	return nil
 }
 
-func (b *Bot) notifyUsersAboutEpisode(show *models.Show, episode *models.Episode) error {
-	userIDs, err := b.dbManager.GetUsersToNotify(episode.ID, show.ID)
+func (b *Bot) notifyUsersAboutEpisode(ctx context.Context, show *models.Show, episode *models.Episode) error {
+	reminders, err := b.dbManager.GetUsersToNotify(episode, b.config.Bot.EpisodeNotificationThreshold)
 	if err != nil {
 		return fmt.Errorf("could not get users to notify: %w", err)
 	}
 
-	if len(userIDs) == 0 {
+	if len(reminders) == 0 {
 		slog.Debug("No users to notify for episode",
 			"showName", show.Name,
 			"episodeName", episode.Name,
@@ -134,43 +414,49 @@ func (b *Bot) notifyUsersAboutEpisode(show *models.Show, episode *models.Episode
 	}
 
 	slog.Info("Notifying users about upcoming episode",
-		"userCount", len(userIDs),
+		"userCount", len(reminders),
 		"show", show.Name,
 		"episode", episode.Name,
-		"airDate", episode.AirDate)
+		"airTime", episode.AirTime())
+
+	for _, reminder := range reminders {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		userID := reminder.UserID
 
-	for _, userID := range userIDs {
 		message := fmt.Sprintf("🔔 *New Episode Alert* 🔔\n\n*%s*\nSeason %d, Episode %d: %s\n\nAirs on %s",
 			show.Name,
 			episode.SeasonNumber,
 			episode.EpisodeNumber,
 			episode.Name,
-			episode.AirDate.Format("Monday, January 2, 2006"),
+			formatEpisodeAirTime(episode, b.userLocation(userID), dateLayout),
 		)
 
 		if episode.Overview != "" {
-			overview := stripHTMLTags(episode.Overview)
-			if len(overview) > 150 {
-				overview = overview[:147] + "..."
-			}
-
-			message += fmt.Sprintf("\n\n%s", overview)
+			message += fmt.Sprintf("\n\n%s", shorten(stripHTMLTags(episode.Overview), 150))
 		}
 
 		// Add information about how soon the episode will air
-		timeUntilAiring := episode.AirDate.Sub(time.Now())
-		if timeUntilAiring < 24*time.Hour {
+		timeUntilAiring := time.Until(episode.AirTime())
+		if timeUntilAiring <= 0 {
+			message += "\n\n📺 This episode is airing now!"
+		} else if timeUntilAiring < 24*time.Hour {
 			message += fmt.Sprintf("\n\n⏰ This episode airs in less than 24 hours!")
 		} else {
 			daysUntil := int(timeUntilAiring.Hours() / 24)
 			message += fmt.Sprintf("\n\n⏰ This episode airs in %d days", daysUntil)
 		}
 
-		b.sendMessage(userID, message)
-
-		err = b.dbManager.RecordNotification(userID, episode.ID)
+		msg, err := newOutboxMessage(b.notificationChat(userID), message, nil)
 		if err != nil {
-			return fmt.Errorf("could not record notification for user %d: %w", userID, err)
+			return err
+		}
+
+		// A failure here leaves the reminder unclaimed, so it is retried on the next check.
+		if err = b.dbManager.EnqueueNotification(userID, msg, episode.ID, reminder.Kinds); err != nil {
+			slog.Error("Error queueing notification", "userID", userID, "episodeID", episode.ID, "err", err)
 		}
 	}
 
@@ -0,0 +1,210 @@
+package bot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+const (
+	outboxPollInterval = 2 * time.Second
+	outboxBatchSize    = 50
+	outboxMaxAttempts  = 8
+	outboxBaseBackoff  = 10 * time.Second
+	outboxMaxBackoff   = time.Hour
+)
+
+// newOutboxMessage builds a queued message; markup may be nil.
+func newOutboxMessage(chatID int64, text string, markup *tgbotapi.InlineKeyboardMarkup) (*models.OutboxMessage, error) {
+	msg := &models.OutboxMessage{ChatID: chatID, Text: text}
+
+	if markup != nil {
+		encoded, err := json.Marshal(markup)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode reply markup: %w", err)
+		}
+
+		msg.ReplyMarkup = string(encoded)
+	}
+
+	return msg, nil
+}
+
+// enqueueMessage queues a plain text message for chatID.
+func (b *Bot) enqueueMessage(chatID int64, text string) error {
+	msg, err := newOutboxMessage(chatID, text, nil)
+	if err != nil {
+		return err
+	}
+
+	return b.dbManager.EnqueueMessage(msg)
+}
+
+// runOutboxDispatcher delivers queued messages until ctx is cancelled.
+func (b *Bot) runOutboxDispatcher(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug("Outbox dispatcher stopped")
+
+			return
+		case <-ticker.C:
+			b.dispatchOutbox(ctx)
+		}
+	}
+}
+
+func (b *Bot) dispatchOutbox(ctx context.Context) {
+	messages, err := b.dbManager.GetDueOutboxMessages(outboxBatchSize)
+	if err != nil {
+		slog.Error("Error querying outbox", "err", err)
+
+		return
+	}
+
+	// Queue the whole batch first so the sender can interleave chats, then record each outcome.
+	results := make([]<-chan sendResult, len(messages))
+
+	for i := range messages {
+		if ctx.Err() != nil {
+			messages = messages[:i]
+
+			break
+		}
+
+		results[i] = b.deliver(&messages[i])
+	}
+
+	for i := range messages {
+		msg := &messages[i]
+
+		if results[i] != nil {
+			sendErr := (<-results[i]).err
+
+			// Messages dropped by the stopping sender were never attempted, so they stay pending.
+			if errors.Is(sendErr, errSenderStopped) {
+				continue
+			}
+
+			b.recordDelivery(msg, sendErr)
+		}
+
+		if err = b.dbManager.UpdateOutboxMessage(msg); err != nil {
+			slog.Error("Error updating outbox message", "id", msg.ID, "status", msg.Status, "err", err)
+		}
+	}
+}
+
+// deliver queues one attempt to send msg on the bulk lane. It returns nil, with msg
+// already failed, when the message cannot be built.
+func (b *Bot) deliver(msg *models.OutboxMessage) <-chan sendResult {
+	config := b.newMessage(msg.ChatID, msg.Text)
+
+	if msg.ReplyMarkup != "" {
+		var markup tgbotapi.InlineKeyboardMarkup
+		if err := json.Unmarshal([]byte(msg.ReplyMarkup), &markup); err != nil {
+			msg.Status = models.OutboxFailed
+			msg.LastError = fmt.Sprintf("invalid reply markup: %v", err)
+
+			return nil
+		}
+
+		config.ReplyMarkup = markup
+	}
+
+	return b.sender.enqueue(msg.ChatID, config, laneBulk)
+}
+
+// recordDelivery sets msg's status after a send attempt that returned err.
+func (b *Bot) recordDelivery(msg *models.OutboxMessage, err error) {
+	msg.Attempts++
+
+	if err == nil {
+		now := time.Now().UTC()
+		msg.Status = models.OutboxSent
+		msg.SentAt = &now
+		msg.LastError = ""
+
+		return
+	}
+
+	msg.LastError = err.Error()
+
+	retryAfter, outcome := classifySendError(err)
+
+	switch {
+	case outcome == models.OutboxBlocked:
+		msg.Status = outcome
+		slog.Warn("Outbox message not deliverable", "id", msg.ID, "chatID", msg.ChatID, "status", outcome, "err", err)
+		b.deactivateUser(msg.ChatID)
+	case outcome != models.OutboxPending:
+		msg.Status = outcome
+		slog.Warn("Outbox message not deliverable", "id", msg.ID, "chatID", msg.ChatID, "status", outcome, "err", err)
+	case msg.Attempts >= outboxMaxAttempts:
+		msg.Status = models.OutboxFailed
+		slog.Error("Outbox message failed permanently", "id", msg.ID, "chatID", msg.ChatID, "attempts", msg.Attempts, "err", err)
+	default:
+		if retryAfter == 0 {
+			retryAfter = min(outboxBaseBackoff<<(msg.Attempts-1), outboxMaxBackoff)
+		}
+
+		msg.NextAttemptAt = time.Now().UTC().Add(retryAfter)
+		slog.Warn("Outbox message will be retried", "id", msg.ID, "chatID", msg.ChatID, "retryIn", retryAfter, "err", err)
+	}
+}
+
+// deactivateIfUnreachable deactivates chatID when err says the chat can no longer be messaged.
+func (b *Bot) deactivateIfUnreachable(chatID int64, err error) {
+	if _, outcome := classifySendError(err); outcome == models.OutboxBlocked {
+		b.deactivateUser(chatID)
+	}
+}
+
+// deactivateUser stops notifications to a user who blocked the bot or deleted their account,
+// or to a group that removed it. An unreachable linked channel is unlinked instead.
+func (b *Bot) deactivateUser(userID int64) {
+	if b.unlinkChannel(userID) {
+		return
+	}
+
+	slog.Info("Deactivating unreachable user", "userID", userID)
+
+	if err := b.dbManager.SetUserActive(userID, false); err != nil {
+		slog.Error("Error deactivating user", "userID", userID, "err", err)
+	}
+}
+
+// classifySendError maps a Telegram send error to a final outbox status, or to
+// OutboxPending with an optional server-requested delay when it is worth retrying.
+// The Bot API reports 429 with retry_after, 403 with a "Forbidden:" description when
+// the user blocked the bot, and "chat not found" for chats that no longer exist.
+func classifySendError(err error) (time.Duration, string) {
+	var apiErr tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		// Transport errors and undecodable responses are transient.
+		return 0, models.OutboxPending
+	}
+
+	switch {
+	case apiErr.RetryAfter > 0:
+		return time.Duration(apiErr.RetryAfter) * time.Second, models.OutboxPending
+	case strings.HasPrefix(apiErr.Message, "Forbidden"),
+		strings.HasSuffix(apiErr.Message, "chat not found"):
+		return 0, models.OutboxBlocked
+	case strings.HasPrefix(apiErr.Message, "Bad Request"):
+		return 0, models.OutboxFailed
+	default:
+		return 0, models.OutboxPending
+	}
+}
@@ -0,0 +1,184 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+func (b *Bot) handleProgressCommand(message *tgbotapi.Message) {
+	text, markup, err := b.progressMenu(subscriberID(message.Chat))
+	if err != nil {
+		slog.Error("Error loading progress", "err", err)
+		b.sendMessage(message.Chat.ID, "An error occurred while fetching your progress.")
+
+		return
+	}
+
+	b.sendMessageWithMarkup(message.Chat.ID, text, markup)
+}
+
+func (b *Bot) displayProgress(chatID int64, messageID, userID int) {
+	text, markup, err := b.progressMenu(userID)
+	if err != nil {
+		slog.Error("Error loading progress", "err", err)
+		b.editMessageWithMenu(
+			chatID,
+			messageID,
+			"An error occurred while fetching your progress.",
+			tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...),
+		)
+
+		return
+	}
+
+	b.editMessageWithMenu(chatID, messageID, text, markup)
+}
+
+// progressMenu lists each followed show's completion and its next unwatched episode,
+// with a button to open that episode.
+func (b *Bot) progressMenu(userID int) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	shows, err := b.dbManager.GetUserShows(userID)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	if len(shows) == 0 {
+		return "📊 *Your Progress*\n\nYou're not following any shows yet. Use the Search option to find shows to follow.",
+			tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...), nil
+	}
+
+	loc := b.userLocation(int64(userID))
+	now := time.Now()
+	text := "📊 *Your Progress*"
+
+	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
+
+	for _, show := range shows {
+		progress, err := b.showProgress(int64(userID), show.ID, now)
+		if err != nil {
+			return "", tgbotapi.InlineKeyboardMarkup{}, err
+		}
+
+		text += fmt.Sprintf("\n\n*%s*\n", show.Name)
+
+		if progress.Aired == 0 && progress.Next == nil {
+			text += "No episodes yet"
+
+			continue
+		}
+
+		text += fmt.Sprintf("%d of %d aired episodes watched (%d%%)", progress.Watched, progress.Aired, progress.Percent())
+
+		if progress.Next == nil {
+			text += "\nAll caught up ✅"
+
+			continue
+		}
+
+		next := progress.Next
+		text += fmt.Sprintf("\nNext: %s %s - %s", episodeCode(*next), next.Name, episodeAirLabel(next, loc, shortDateLayout))
+
+		inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				shorten(fmt.Sprintf("▶️ %s %s", show.Name, episodeCode(*next)), 40),
+				fmt.Sprintf("%s:%s", ActionEpisode, next.ID),
+			),
+		))
+	}
+
+	inlineKeyboard = append(inlineKeyboard, b.createHomeButton()...)
+
+	return text, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...), nil
+}
+
+func (b *Bot) showProgress(userID int64, showID string, now time.Time) (models.Progress, error) {
+	episodes, err := b.dbManager.GetEpisodesForShow(showID)
+	if err != nil {
+		return models.Progress{}, err
+	}
+
+	watched, err := b.watchedSet(userID, showID)
+	if err != nil {
+		return models.Progress{}, err
+	}
+
+	return models.ShowProgress(episodes, watched, now), nil
+}
+
+func (b *Bot) watchedSet(userID int64, showID string) (map[string]bool, error) {
+	episodeIDs, err := b.dbManager.GetWatchedEpisodes(userID, showID)
+	if err != nil {
+		return nil, err
+	}
+
+	watched := make(map[string]bool, len(episodeIDs))
+	for _, episodeID := range episodeIDs {
+		watched[episodeID] = true
+	}
+
+	return watched, nil
+}
+
+// markWatched applies a watch action to an episode and returns how many episodes it covered.
+func (b *Bot) markWatched(userID int64, episodeID, action string) (int, error) {
+	if action == ActionUnwatch {
+		return 1, b.dbManager.UnmarkEpisodeWatched(userID, episodeID)
+	}
+
+	episode, err := b.dbManager.GetEpisode(episodeID)
+	if err != nil {
+		return 0, err
+	}
+
+	episodeIDs := []string{episode.ID}
+
+	if action != ActionWatch {
+		episodes, err := b.dbManager.GetEpisodesForShow(episode.ShowID)
+		if err != nil {
+			return 0, err
+		}
+
+		episodeIDs = episodesToMark(episodes, episode, action)
+	}
+
+	return len(episodeIDs), b.dbManager.MarkEpisodesWatched(userID, episodeIDs)
+}
+
+// episodesToMark returns the episodes, in season order, that "mark season" or "mark all up to here"
+// on episode covers. Specials only ever mark other specials, and regular episodes never mark specials.
+func episodesToMark(episodes []models.Episode, episode *models.Episode, action string) []string {
+	special := episode.SeasonNumber == specialsSeason
+
+	var episodeIDs []string
+
+	for _, other := range episodes {
+		switch {
+		case action == ActionWatchSeason && other.SeasonNumber == episode.SeasonNumber:
+			episodeIDs = append(episodeIDs, other.ID)
+		case action == ActionWatchUpTo && (other.SeasonNumber == specialsSeason) == special:
+			episodeIDs = append(episodeIDs, other.ID)
+		}
+
+		if action == ActionWatchUpTo && other.ID == episode.ID {
+			break
+		}
+	}
+
+	return episodeIDs
+}
+
+func watchedMessage(action string, count int) string {
+	switch {
+	case action == ActionUnwatch:
+		return "Marked as not watched"
+	case count == 1:
+		return "Marked as watched"
+	default:
+		return fmt.Sprintf("Marked %d episodes as watched", count)
+	}
+}
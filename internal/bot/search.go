@@ -1,11 +1,16 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"maps"
 	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
 
@@ -14,25 +19,216 @@ import (
 
 var htmlRegexp = regexp.MustCompile(`<[^>]*>`)
 
+const (
+	// searchSessionTTL is how long a chat's search results can be paged through.
+	searchSessionTTL = time.Hour
+
+	defaultSearchPageSize = 5
+
+	// searchCacheTTL is how long merged provider results are reused for the same query;
+	// inline mode searches again on every keystroke.
+	searchCacheTTL    = 10 * time.Minute
+	maxCachedSearches = 1000
+)
+
+// SearchSession is a chat's latest search; its results are kept so pages can be browsed without searching again.
 type SearchSession struct {
-	Query    string
-	Results  []models.Show
-	Page     int
-	PageSize int
+	Query     string
+	Results   []models.Show
+	Page      int
+	PageSize  int
+	UpdatedAt time.Time
+}
+
+// Pages returns the number of result pages, at least one.
+func (s SearchSession) Pages() int {
+	return max(1, (len(s.Results)+s.PageSize-1)/s.PageSize)
+}
+
+// PageResults returns the shows on the current page.
+func (s SearchSession) PageResults() []models.Show {
+	start := min(s.Page*s.PageSize, len(s.Results))
+	end := min(start+s.PageSize, len(s.Results))
+
+	return s.Results[start:end]
+}
+
+// searchSessions holds the latest search of each chat.
+type searchSessions struct {
+	mu       sync.Mutex
+	sessions map[int64]SearchSession
+}
+
+func newSearchSessions() *searchSessions {
+	return &searchSessions{sessions: make(map[int64]SearchSession)}
+}
+
+// put replaces the chat's session and forgets expired ones.
+func (s *searchSessions) put(chatID int64, session SearchSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for id, old := range s.sessions {
+		if now.Sub(old.UpdatedAt) > searchSessionTTL {
+			delete(s.sessions, id)
+		}
+	}
+
+	session.UpdatedAt = now
+	s.sessions[chatID] = session
+}
+
+// turn moves the chat's session to page, clamped to the available pages, and returns it.
+// It reports false when the chat has no unexpired session.
+func (s *searchSessions) turn(chatID int64, page int) (SearchSession, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[chatID]
+	if !ok || time.Since(session.UpdatedAt) > searchSessionTTL {
+		delete(s.sessions, chatID)
+
+		return SearchSession{}, false
+	}
+
+	session.Page = max(0, min(page, session.Pages()-1))
+	session.UpdatedAt = time.Now()
+	s.sessions[chatID] = session
+
+	return session, true
+}
+
+// current returns the chat's session at the page last shown.
+func (s *searchSessions) current(chatID int64) (SearchSession, bool) {
+	s.mu.Lock()
+	page := s.sessions[chatID].Page
+	s.mu.Unlock()
+
+	return s.turn(chatID, page)
+}
+
+// searchCache holds recent merged search results by normalized query.
+type searchCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedSearch
+}
+
+type cachedSearch struct {
+	results  []models.Show
+	storedAt time.Time
+}
+
+func newSearchCache() *searchCache {
+	return &searchCache{entries: make(map[string]cachedSearch)}
+}
+
+func searchCacheKey(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
 }
 
-func (b *Bot) enhanceSearchResults(chatID int64, query string, results []models.Show) {
-	if len(results) == 0 {
-		b.sendMessageWithMarkup(chatID, fmt.Sprintf("No shows found for: %s", query), b.createMainMenu())
+func (c *searchCache) get(query string) ([]models.Show, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := searchCacheKey(query)
+
+	entry, ok := c.entries[key]
+	if !ok || time.Since(entry.storedAt) > searchCacheTTL {
+		delete(c.entries, key)
+
+		return nil, false
+	}
+
+	return entry.results, true
+}
+
+// put stores results, dropping expired entries and, when the cache is still full, the oldest one.
+func (c *searchCache) put(query string, results []models.Show) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	var oldestKey string
+
+	for key, entry := range c.entries {
+		if now.Sub(entry.storedAt) > searchCacheTTL {
+			delete(c.entries, key)
+
+			continue
+		}
+
+		if oldestKey == "" || entry.storedAt.Before(c.entries[oldestKey].storedAt) {
+			oldestKey = key
+		}
+	}
+
+	if len(c.entries) >= maxCachedSearches {
+		delete(c.entries, oldestKey)
+	}
+
+	c.entries[searchCacheKey(query)] = cachedSearch{results: results, storedAt: now}
+}
+
+func (b *Bot) enhanceSearchResults(chatID int64, session SearchSession) {
+	if len(session.Results) == 0 {
+		b.sendMessageWithMarkup(chatID, fmt.Sprintf("No shows found for: %s", session.Query), b.createMainMenu())
+		return
+	}
+
+	text, markup := b.searchResultsPage(chatID, session)
+	b.sendMessageWithMarkup(chatID, text, markup)
+}
+
+// displaySearchResults shows the chat's search results at page in place of messageID.
+func (b *Bot) displaySearchResults(chatID int64, messageID, page int) {
+	session, ok := b.searches.turn(chatID, page)
+	if !ok {
+		b.showSearchExpired(chatID, messageID)
+
 		return
 	}
 
-	text := fmt.Sprintf("🔍 *Search Results for \"%s\"*\n", query)
-	text += fmt.Sprintf("Found %d shows. Select for more options:", len(results))
+	text, markup := b.searchResultsPage(chatID, session)
+	b.editMessageWithMenu(chatID, messageID, text, markup)
+}
+
+// displayLastSearchResults returns to the page of search results the chat last saw.
+func (b *Bot) displayLastSearchResults(chatID int64, messageID int) {
+	session, ok := b.searches.current(chatID)
+	if !ok {
+		b.showSearchExpired(chatID, messageID)
+
+		return
+	}
+
+	text, markup := b.searchResultsPage(chatID, session)
+	b.editMessageWithMenu(chatID, messageID, text, markup)
+}
+
+func (b *Bot) showSearchExpired(chatID int64, messageID int) {
+	b.editMessageWithMenu(
+		chatID,
+		messageID,
+		"🔍 These search results have expired. Type the name of a show to search again.",
+		tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...),
+	)
+}
+
+func (b *Bot) searchResultsPage(chatID int64, session SearchSession) (string, tgbotapi.InlineKeyboardMarkup) {
+	text := fmt.Sprintf("🔍 *Search Results for \"%s\"*\n", session.Query)
+	text += fmt.Sprintf("Found %d shows", len(session.Results))
+
+	if session.Pages() > 1 {
+		text += fmt.Sprintf(", page %d of %d", session.Page+1, session.Pages())
+	}
+
+	text += ". Select for more options:"
 
 	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
 
-	for _, show := range results {
+	for _, show := range session.PageResults() {
 		status := ""
 
 		if show.Status != "" {
@@ -59,17 +255,12 @@ func (b *Bot) enhanceSearchResults(chatID int64, query string, results []models.
 		)
 
 		if show.Overview != "" {
-			overview := stripHTMLTags(show.Overview)
-			if len(overview) > 100 {
-				overview = overview[:97] + "..."
-			}
-
-			text += fmt.Sprintf("\n  %s", overview)
+			text += fmt.Sprintf("\n  %s", shorten(stripHTMLTags(show.Overview), 100))
 		}
 
 		detailsButton := tgbotapi.NewInlineKeyboardButtonData(
 			"📋 Details",
-			fmt.Sprintf("%s:%s", ActionDetails, show.ID),
+			fmt.Sprintf("%s:%s", ActionResult, show.ID),
 		)
 
 		followed, err := b.dbManager.IsShowFollowed(chatID, show.ID)
@@ -95,52 +286,96 @@ func (b *Bot) enhanceSearchResults(chatID int64, query string, results []models.
 		inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(detailsButton, followButton))
 	}
 
+	var pager []tgbotapi.InlineKeyboardButton
+
+	if session.Page > 0 {
+		pager = append(pager, tgbotapi.NewInlineKeyboardButtonData("◀️ Prev", fmt.Sprintf("%s:%d", ActionPage, session.Page-1)))
+	}
+
+	if session.Page < session.Pages()-1 {
+		pager = append(pager, tgbotapi.NewInlineKeyboardButtonData("Next ▶️", fmt.Sprintf("%s:%d", ActionPage, session.Page+1)))
+	}
+
+	if len(pager) > 0 {
+		inlineKeyboard = append(inlineKeyboard, pager)
+	}
+
 	inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
 		tgbotapi.NewInlineKeyboardButtonData("🏠 Home", MenuMain),
 		tgbotapi.NewInlineKeyboardButtonData("🔍 New Search", MenuSearch),
 	))
 
-	b.sendMessageWithMarkup(chatID, text, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...))
+	return text, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...)
 }
 
 func stripHTMLTags(s string) string {
 	return htmlRegexp.ReplaceAllString(s, " ")
 }
 
+// markdownSpecialChars are the characters MarkdownV2 requires to be escaped.
+const markdownSpecialChars = "_*[]()~`>#+-=|{}.!"
+
 func escapeMarkdown(text string) string {
-	specialChars := []string{"_", "*", "[", "]", "(", ")", "~", "`", ">", "#", "+", "-", "=", "|", "{", "}", ".", "!"}
-	for _, char := range specialChars {
-		text = strings.ReplaceAll(text, char, "\\"+char)
+	for _, char := range markdownSpecialChars {
+		text = strings.ReplaceAll(text, string(char), "\\"+string(char))
 	}
 
 	return text
 }
 
-func (b *Bot) searchShows(chatID int64, query string) {
+func (b *Bot) searchShows(ctx context.Context, chatID int64, query string) {
+	mergedResults := b.findShows(ctx, query)
+	if len(mergedResults) == 0 {
+		b.sendMessage(chatID, fmt.Sprintf("No shows found for query: %s", query))
+
+		return
+	}
+
+	pageSize := b.config.Bot.MaxResults
+	if pageSize <= 0 {
+		pageSize = defaultSearchPageSize
+	}
+
+	session := SearchSession{Query: query, Results: mergedResults, PageSize: pageSize}
+	b.searches.put(chatID, session)
+
+	b.enhanceSearchResults(chatID, session)
+}
+
+// findShows searches every provider, merges results describing the same IMDb title and stores
+// the shows so they can be followed. Results are cached per query for searchCacheTTL.
+func (b *Bot) findShows(ctx context.Context, query string) []models.Show {
+	if cached, ok := b.searchCache.get(query); ok {
+		return cached
+	}
+
 	allResults := make([]models.Show, 0)
+	complete := true
 
-	for providerName, client := range b.apiClients {
-		results, err := client.SearchShows(query)
+	// Providers are searched in name order so merged results come back in the same order every time.
+	for _, providerName := range slices.Sorted(maps.Keys(b.apiClients)) {
+		results, err := b.apiClients[providerName].SearchShows(ctx, query)
 		if err != nil {
 			log.Printf("Error searching shows with %s: %v", providerName, err)
 
+			complete = false
+
 			continue
 		}
 
 		allResults = append(allResults, results...)
 	}
 
-	if len(allResults) == 0 {
-		b.sendMessage(chatID, fmt.Sprintf("No shows found for query: %s", query))
-
-		return
-	}
-
 	showsByIMDb := make(map[string][]models.Show)
+	imdbIDs := make([]string, 0) // in order of first appearance
 	showsWithoutIMDb := make([]models.Show, 0)
 
 	for _, show := range allResults {
 		if show.IMDbID != "" {
+			if _, seen := showsByIMDb[show.IMDbID]; !seen {
+				imdbIDs = append(imdbIDs, show.IMDbID)
+			}
+
 			showsByIMDb[show.IMDbID] = append(showsByIMDb[show.IMDbID], show)
 		} else {
 			showsWithoutIMDb = append(showsWithoutIMDb, show)
@@ -149,8 +384,9 @@ func (b *Bot) searchShows(chatID int64, query string) {
 
 	mergedResults := make([]models.Show, 0)
 
-	for _, shows := range showsByIMDb {
-		sort.Slice(shows, func(i, j int) bool {
+	for _, imdbID := range imdbIDs {
+		shows := showsByIMDb[imdbID]
+		sort.SliceStable(shows, func(i, j int) bool {
 			iScore := 0
 			jScore := 0
 
@@ -206,10 +442,10 @@ func (b *Bot) searchShows(chatID int64, query string) {
 		mergedResults = append(mergedResults, show)
 	}
 
-	maxResults := b.config.Bot.MaxResults
-	if len(mergedResults) > maxResults {
-		mergedResults = mergedResults[:maxResults]
+	// A provider failure would otherwise be cached as missing results.
+	if complete {
+		b.searchCache.put(query, mergedResults)
 	}
 
-	b.enhanceSearchResults(chatID, query, mergedResults)
+	return mergedResults
 }
@@ -0,0 +1,332 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/clients"
+)
+
+// sendLane is a priority class of the send queue; lower lanes always go first.
+type sendLane int
+
+const (
+	laneInteractive sendLane = iota // replies to something the user just did
+	laneBulk                        // notifications, digests and other broadcasts
+	laneCount
+)
+
+const (
+	defaultSendRateLimit     = 30 // messages per second across all chats
+	defaultChatSendRateLimit = 1  // messages per second to a single chat
+
+	// chatSendBurst lets a short multi-message reply through without waiting on the per-chat rate.
+	chatSendBurst = 3
+
+	chatIdleTimeout     = time.Minute
+	senderStatsInterval = time.Minute
+
+	// slowInteractiveWait is the queue wait after which interactive replies count as laggy.
+	slowInteractiveWait = time.Second
+)
+
+var errSenderStopped = errors.New("telegram sender stopped")
+
+type sendResult struct {
+	msg tgbotapi.Message
+	err error
+}
+
+type sendJob struct {
+	chatID   int64
+	config   tgbotapi.Chattable
+	queuedAt time.Time
+	done     chan sendResult
+}
+
+type chatState struct {
+	limiter     *clients.RateLimiter
+	busy        bool      // a send to this chat is in flight
+	pausedUntil time.Time // set from a 429 retry_after
+	lastUsed    time.Time
+}
+
+// senderStats are the backpressure counters reported every senderStatsInterval.
+type senderStats struct {
+	sent, failed, throttled int
+	maxWait                 [laneCount]time.Duration
+}
+
+// sender is the single way messages reach Telegram. It applies a global and a
+// per-chat token bucket, keeps each chat's messages in order, and always serves
+// the interactive lane before the bulk one, so broadcasts never delay menus.
+type sender struct {
+	send     func(tgbotapi.Chattable) (tgbotapi.Message, error)
+	global   *clients.RateLimiter
+	chatRate int
+
+	mu      sync.Mutex
+	lanes   [laneCount][]*sendJob
+	chats   map[int64]*chatState
+	stats   senderStats
+	stopped bool
+	wake    chan struct{}
+}
+
+func newSender(send func(tgbotapi.Chattable) (tgbotapi.Message, error), rate, chatRate int) *sender {
+	if rate == 0 {
+		rate = defaultSendRateLimit
+	}
+
+	if chatRate == 0 {
+		chatRate = defaultChatSendRateLimit
+	}
+
+	return &sender{
+		send:     send,
+		global:   clients.NewRateLimiter(rate, time.Second),
+		chatRate: chatRate,
+		chats:    make(map[int64]*chatState),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// enqueue queues config for chatID; the result arrives on the returned channel once it was sent.
+func (s *sender) enqueue(chatID int64, config tgbotapi.Chattable, lane sendLane) <-chan sendResult {
+	job := &sendJob{chatID: chatID, config: config, queuedAt: time.Now(), done: make(chan sendResult, 1)}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		job.done <- sendResult{err: errSenderStopped}
+
+		return job.done
+	}
+
+	s.lanes[lane] = append(s.lanes[lane], job)
+	s.signal()
+
+	return job.done
+}
+
+// sendNow queues config and waits until it was sent.
+func (s *sender) sendNow(chatID int64, config tgbotapi.Chattable, lane sendLane) (tgbotapi.Message, error) {
+	result := <-s.enqueue(chatID, config, lane)
+
+	return result.msg, result.err
+}
+
+func (s *sender) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends queued messages until ctx is cancelled, then fails whatever is still queued.
+// The global token is taken before a job is chosen, so a reply queued while the bucket
+// refills still goes ahead of bulk messages that were waiting longer.
+func (s *sender) run(ctx context.Context) {
+	ticker := time.NewTicker(senderStatsInterval)
+	defer ticker.Stop()
+
+	haveToken := false
+
+	for {
+		var wait time.Duration
+
+		if s.queued() {
+			if !haveToken {
+				if err := s.global.Wait(ctx); err != nil {
+					s.abort()
+
+					return
+				}
+
+				haveToken = true
+			}
+
+			var job *sendJob
+			if job, wait = s.next(); job != nil {
+				haveToken = false
+
+				go s.execute(job)
+
+				continue
+			}
+		}
+
+		var timer <-chan time.Time
+		if wait > 0 {
+			timer = time.After(wait)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.abort()
+
+			return
+		case <-s.wake:
+		case <-timer:
+		case <-ticker.C:
+			s.reportStats()
+		}
+	}
+}
+
+func (s *sender) queued() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.lanes[laneInteractive])+len(s.lanes[laneBulk]) > 0
+}
+
+// next takes the first job, interactive lane first, whose chat may be sent to now. With
+// nothing ready it returns how long until a throttled chat frees up, or 0 to wait for a signal.
+func (s *sender) next() (*sendJob, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	var wait time.Duration
+
+	later := func(d time.Duration) {
+		if wait == 0 || d < wait {
+			wait = d
+		}
+	}
+
+	for lane := range s.lanes {
+		for i, job := range s.lanes[lane] {
+			chat := s.chat(job.chatID, now)
+
+			if chat.busy {
+				continue
+			}
+
+			if now.Before(chat.pausedUntil) {
+				later(chat.pausedUntil.Sub(now))
+
+				continue
+			}
+
+			if delay := chat.limiter.Reserve(); delay > 0 {
+				later(delay)
+
+				continue
+			}
+
+			chat.busy = true
+			s.lanes[lane] = slices.Delete(s.lanes[lane], i, i+1)
+			s.stats.maxWait[lane] = max(s.stats.maxWait[lane], now.Sub(job.queuedAt))
+
+			return job, 0
+		}
+	}
+
+	return nil, wait
+}
+
+func (s *sender) chat(chatID int64, now time.Time) *chatState {
+	chat, ok := s.chats[chatID]
+	if !ok {
+		window := time.Duration(chatSendBurst) * time.Second / time.Duration(s.chatRate)
+		chat = &chatState{limiter: clients.NewRateLimiter(chatSendBurst, window), lastUsed: now}
+		s.chats[chatID] = chat
+	}
+
+	return chat
+}
+
+func (s *sender) execute(job *sendJob) {
+	msg, err := s.send(job.config)
+	s.finish(job, msg, err)
+}
+
+// finish releases the job's chat and delivers its result. A 429 pauses the chat for retry_after.
+func (s *sender) finish(job *sendJob, msg tgbotapi.Message, err error) {
+	s.mu.Lock()
+
+	now := time.Now()
+	chat := s.chat(job.chatID, now)
+	chat.busy = false
+	chat.lastUsed = now
+
+	var apiErr tgbotapi.Error
+
+	switch {
+	case err == nil:
+		s.stats.sent++
+	case errors.As(err, &apiErr) && apiErr.RetryAfter > 0:
+		chat.pausedUntil = now.Add(time.Duration(apiErr.RetryAfter) * time.Second)
+		s.stats.throttled++
+		s.stats.failed++
+	default:
+		s.stats.failed++
+	}
+
+	s.mu.Unlock()
+
+	s.signal()
+
+	job.done <- sendResult{msg: msg, err: err}
+}
+
+func (s *sender) abort() {
+	s.mu.Lock()
+	lanes := s.lanes
+	s.lanes = [laneCount][]*sendJob{}
+	s.stopped = true
+	s.mu.Unlock()
+
+	for _, jobs := range lanes {
+		for _, job := range jobs {
+			job.done <- sendResult{err: errSenderStopped}
+		}
+	}
+}
+
+// reportStats logs queue depth and wait times for the last interval and forgets idle chats.
+func (s *sender) reportStats() {
+	s.mu.Lock()
+
+	stats := s.stats
+	s.stats = senderStats{}
+	interactive, bulk := len(s.lanes[laneInteractive]), len(s.lanes[laneBulk])
+
+	now := time.Now()
+	for chatID, chat := range s.chats {
+		if !chat.busy && now.Sub(chat.lastUsed) > chatIdleTimeout {
+			delete(s.chats, chatID)
+		}
+	}
+
+	s.mu.Unlock()
+
+	if stats.sent+stats.failed == 0 && interactive+bulk == 0 {
+		return
+	}
+
+	level := slog.LevelInfo
+	if stats.throttled > 0 || stats.maxWait[laneInteractive] > slowInteractiveWait {
+		level = slog.LevelWarn
+	}
+
+	slog.Log(context.Background(), level, "Telegram send queue",
+		"sent", stats.sent,
+		"failed", stats.failed,
+		"throttled", stats.throttled,
+		"queuedInteractive", interactive,
+		"queuedBulk", bulk,
+		"maxWaitInteractive", stats.maxWait[laneInteractive],
+		"maxWaitBulk", stats.maxWait[laneBulk],
+	)
+}
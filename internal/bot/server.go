@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// newServeMux returns the routes the embedded HTTP server serves in every mode.
+func (b *Bot) newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	if b.config.Calendar.Enabled {
+		mux.HandleFunc("GET "+calendarPath+"{file}", b.handleCalendarFeed)
+	}
+
+	return mux
+}
+
+// serveHTTP runs the embedded HTTP server until ctx is cancelled, then shuts it down
+// within Server.ShutdownTimeout.
+func (b *Bot) serveHTTP(ctx context.Context, handler http.Handler) error {
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", b.config.Server.Port),
+		Handler:      handler,
+		ReadTimeout:  b.config.Server.ReadTimeout,
+		WriteTimeout: b.config.Server.WriteTimeout,
+	}
+
+	slog.Info("Starting HTTP server", "addr", server.Addr)
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+
+		close(serveErr)
+	}()
+
+	select {
+	case err := <-serveErr:
+		if err != nil {
+			return fmt.Errorf("HTTP server failed: %w", err)
+		}
+
+		return nil
+	case <-ctx.Done():
+	}
+
+	slog.Info("Stopping HTTP server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), b.shutdownTimeout())
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to stop HTTP server: %w", err)
+	}
+
+	return nil
+}
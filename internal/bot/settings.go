@@ -0,0 +1,276 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+// quietHoursPresets are the quiet-hour windows the settings menu cycles through; the first is "off".
+var quietHoursPresets = []struct{ start, end int }{
+	{0, 0},
+	{22, 8},
+	{23, 7},
+	{0, 9},
+}
+
+func (b *Bot) handleSettingsCommand(message *tgbotapi.Message) {
+	text, markup, err := b.settingsMenu(int64(subscriberID(message.Chat)))
+	if err != nil {
+		slog.Error("Error loading settings", "err", err)
+		b.sendMessage(message.Chat.ID, "An error occurred while loading your settings.")
+
+		return
+	}
+
+	b.sendMessageWithMarkup(message.Chat.ID, text, markup)
+}
+
+func (b *Bot) displaySettings(chatID int64, messageID int, userID int64) {
+	text, markup, err := b.settingsMenu(userID)
+	if err != nil {
+		slog.Error("Error loading settings", "err", err)
+		b.editMessageWithMenu(
+			chatID,
+			messageID,
+			"An error occurred while loading your settings.",
+			tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...),
+		)
+
+		return
+	}
+
+	b.editMessageWithMenu(chatID, messageID, text, markup)
+}
+
+func (b *Bot) settingsMenu(userID int64) (string, tgbotapi.InlineKeyboardMarkup, error) {
+	settings, err := b.dbManager.GetUserSettings(userID)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	muted, err := b.dbManager.GetMutedShows(userID)
+	if err != nil {
+		return "", tgbotapi.InlineKeyboardMarkup{}, err
+	}
+
+	text := "⚙️ *Notification Settings*\n\n"
+
+	if settings.LeadTimes == "" {
+		text += fmt.Sprintf("Reminders: default (once, up to %s before airing)\n",
+			formatLead(b.config.Bot.EpisodeNotificationThreshold))
+	} else {
+		labels := make([]string, 0, len(models.LeadTimes))
+		for _, lead := range settings.Leads(b.config.Bot.EpisodeNotificationThreshold) {
+			labels = append(labels, strings.ToLower(lead.Label))
+		}
+
+		text += fmt.Sprintf("Reminders: %s\n", strings.Join(labels, ", "))
+	}
+
+	text += fmt.Sprintf("Quiet hours: %s (%s)\n", formatQuietHours(settings), b.userLocation(userID))
+	text += fmt.Sprintf("Digest: %s\n", formatDigest(settings))
+	text += fmt.Sprintf("Muted shows: %d\n\nTap a reminder to turn it on or off.", len(muted))
+
+	if settings.Digest != models.DigestOff {
+		text += " With a digest on, reminders a day or more ahead are replaced by the digest."
+	}
+
+	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
+
+	var row []tgbotapi.InlineKeyboardButton
+
+	for _, lead := range models.LeadTimes {
+		mark := "☐"
+		if settings.HasLead(lead.Kind) {
+			mark = "✅"
+		}
+
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("%s %s", mark, lead.Label),
+			fmt.Sprintf("%s:%s", ActionLead, lead.Kind),
+		))
+
+		if len(row) == 2 {
+			inlineKeyboard = append(inlineKeyboard, row)
+			row = nil
+		}
+	}
+
+	inlineKeyboard = append(inlineKeyboard,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("🌙 Quiet hours: %s", formatQuietHours(settings)),
+				fmt.Sprintf("%s:next", ActionQuiet),
+			),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("🗞 Digest: %s", digestMode(settings)),
+				fmt.Sprintf("%s:mode", ActionDigest),
+			),
+		),
+	)
+
+	if settings.Digest != models.DigestOff {
+		row = tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonData(
+			fmt.Sprintf("🕗 At %02d:00", settings.DigestHour),
+			fmt.Sprintf("%s:hour", ActionDigest),
+		))
+
+		if settings.Digest == models.DigestWeekly {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("📆 On %s", settings.DigestWeekday),
+				fmt.Sprintf("%s:day", ActionDigest),
+			))
+		}
+
+		inlineKeyboard = append(inlineKeyboard, row)
+	}
+
+	inlineKeyboard = append(inlineKeyboard,
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🔕 Mute shows", MenuMutedShows),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏠 Home", MenuMain),
+		),
+	)
+
+	return text, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...), nil
+}
+
+func formatLead(d time.Duration) string {
+	day := 24 * time.Hour
+	if d >= day && d%day == 0 {
+		if d == day {
+			return "1 day"
+		}
+
+		return fmt.Sprintf("%d days", d/day)
+	}
+
+	return d.String()
+}
+
+func digestMode(settings *models.UserSettings) string {
+	if settings.Digest == models.DigestOff {
+		return "off"
+	}
+
+	return settings.Digest
+}
+
+func formatQuietHours(settings *models.UserSettings) string {
+	if !settings.QuietHours {
+		return "off"
+	}
+
+	return fmt.Sprintf("%02d:00–%02d:00", settings.QuietStart, settings.QuietEnd)
+}
+
+func (b *Bot) toggleLeadTime(userID int64, kind string) error {
+	settings, err := b.dbManager.GetUserSettings(userID)
+	if err != nil {
+		return err
+	}
+
+	settings.ToggleLead(kind)
+
+	return b.dbManager.SaveUserSettings(settings)
+}
+
+// cycleQuietHours moves the user's quiet hours to the next preset.
+func (b *Bot) cycleQuietHours(userID int64) error {
+	settings, err := b.dbManager.GetUserSettings(userID)
+	if err != nil {
+		return err
+	}
+
+	current := 0
+
+	for i, preset := range quietHoursPresets {
+		if i > 0 && settings.QuietHours && preset.start == settings.QuietStart && preset.end == settings.QuietEnd {
+			current = i
+		}
+	}
+
+	next := quietHoursPresets[(current+1)%len(quietHoursPresets)]
+	settings.QuietHours = next.start != next.end
+	settings.QuietStart, settings.QuietEnd = next.start, next.end
+
+	return b.dbManager.SaveUserSettings(settings)
+}
+
+func (b *Bot) displayMutedShows(chatID int64, messageID int, userID int64) {
+	shows, err := b.dbManager.GetUserShows(int(userID))
+	if err != nil {
+		slog.Error("Error getting user shows", "err", err)
+		b.editMessageWithMenu(
+			chatID,
+			messageID,
+			"An error occurred while fetching your shows.",
+			tgbotapi.NewInlineKeyboardMarkup(b.createHomeButton()...),
+		)
+
+		return
+	}
+
+	muted, err := b.dbManager.GetMutedShows(userID)
+	if err != nil {
+		slog.Error("Error getting muted shows", "err", err)
+	}
+
+	isMuted := make(map[string]bool, len(muted))
+	for _, showID := range muted {
+		isMuted[showID] = true
+	}
+
+	text := "🔕 *Mute Shows*\n\nMuted shows send no episode reminders. Tap a show to mute or unmute it."
+	if len(shows) == 0 {
+		text = "🔕 *Mute Shows*\n\nYou're not following any shows yet."
+	}
+
+	var inlineKeyboard [][]tgbotapi.InlineKeyboardButton
+
+	for _, show := range shows {
+		icon := "🔔"
+		if isMuted[show.ID] {
+			icon = "🔕"
+		}
+
+		inlineKeyboard = append(inlineKeyboard, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(
+				fmt.Sprintf("%s %s", icon, show.Name),
+				fmt.Sprintf("%s:%s", ActionMute, show.ID),
+			),
+		))
+	}
+
+	inlineKeyboard = append(inlineKeyboard, b.createBackHomeRow(MenuSettings))
+
+	b.editMessageWithMenu(chatID, messageID, text, tgbotapi.NewInlineKeyboardMarkup(inlineKeyboard...))
+}
+
+// toggleShowMuted flips the mute override for one of the user's shows and returns the new state.
+func (b *Bot) toggleShowMuted(userID int64, showID string) (bool, error) {
+	muted, err := b.dbManager.GetMutedShows(userID)
+	if err != nil {
+		return false, err
+	}
+
+	mute := true
+
+	for _, id := range muted {
+		if id == showID {
+			mute = false
+		}
+	}
+
+	return mute, b.dbManager.SetShowMuted(userID, showID, mute)
+}
@@ -0,0 +1,634 @@
+// Package telegramtest is an in-process fake of the Telegram Bot API for end-to-end tests.
+//
+// The server records every call the bot makes and serves updates injected by the test
+// through getUpdates, so a bot running in polling mode can be driven without network:
+//
+//	func TestStart(t *testing.T) {
+//		server := telegramtest.NewServer(t)
+//		b := bot.NewWithDeps(cfg, bot.Deps{Messenger: server.Messenger(t), ...})
+//		go b.Start(ctx)
+//
+//		server.SendText(telegramtest.User(1), "/start")
+//		calls := server.WaitCalls(t, "sendMessage", 1)
+//	}
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/bot"
+)
+
+// Token is the bot token the fake accepts.
+const Token = "123456:TEST-TOKEN"
+
+const (
+	// WaitTimeout bounds how long WaitCalls waits for the bot.
+	WaitTimeout = 5 * time.Second
+
+	// maxPollWait caps getUpdates long polls so tests shut down quickly.
+	maxPollWait = time.Second
+)
+
+// BotUser is the account the fake reports from getMe.
+var BotUser = tgbotapi.User{ID: 123456, FirstName: "Shows", UserName: "shows_test_bot", IsBot: true}
+
+const (
+	// maxUploadSize bounds the documents the bot may upload to the fake.
+	maxUploadSize = 10 << 20
+
+	filePathPrefix = "/file/bot" + Token + "/"
+)
+
+// Call is one Bot API request made by the bot.
+type Call struct {
+	Method string
+	Params url.Values
+	Files  map[string][]byte // uploaded files by form field, e.g. "document"
+}
+
+// ChatID returns the chat the call was addressed to.
+func (c Call) ChatID() int64 {
+	id, _ := strconv.ParseInt(c.Params.Get("chat_id"), 10, 64)
+
+	return id
+}
+
+// Text returns the message text of a sendMessage or editMessageText call.
+func (c Call) Text() string {
+	return c.Params.Get("text")
+}
+
+// Buttons returns the callback data of the call's inline keyboard, row by row.
+func (c Call) Buttons() [][]string {
+	var markup tgbotapi.InlineKeyboardMarkup
+	if err := json.Unmarshal([]byte(c.Params.Get("reply_markup")), &markup); err != nil {
+		return nil
+	}
+
+	rows := make([][]string, len(markup.InlineKeyboard))
+
+	for i, row := range markup.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil {
+				rows[i] = append(rows[i], *button.CallbackData)
+			}
+		}
+	}
+
+	return rows
+}
+
+// User returns a private-chat user with the given ID.
+func User(id int) tgbotapi.User {
+	return tgbotapi.User{ID: id, FirstName: fmt.Sprintf("User %d", id), UserName: fmt.Sprintf("user%d", id)}
+}
+
+// Server is a fake Bot API served over httptest.
+type Server struct {
+	server *httptest.Server
+
+	mu            sync.Mutex
+	calls         []Call
+	updates       []tgbotapi.Update
+	nextUpdateID  int
+	nextMessageID int
+	failures      map[int64]failure // errors returned for messages to a chat
+	files         map[string][]byte // documents users sent, by file ID
+	chats         map[int64]tgbotapi.Chat
+	members       map[int64]map[int]string // member status by chat and user
+	changed       chan struct{}            // closed and replaced whenever updates are added
+}
+
+// NewServer starts a fake Bot API that is closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		nextUpdateID:  1,
+		nextMessageID: 1,
+		failures:      make(map[int64]failure),
+		files:         make(map[string][]byte),
+		chats:         make(map[int64]tgbotapi.Chat),
+		members:       make(map[int64]map[int]string),
+		changed:       make(chan struct{}),
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// URL returns the base URL of the fake.
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns an HTTP client that sends api.telegram.org requests to the fake.
+func (s *Server) Client() *http.Client {
+	target, _ := url.Parse(s.server.URL)
+
+	return &http.Client{Transport: &redirectTransport{target: target, next: s.server.Client().Transport}}
+}
+
+// Messenger connects a real Bot API client to the fake.
+func (s *Server) Messenger(t testing.TB) bot.Messenger {
+	t.Helper()
+
+	messenger, err := bot.NewMessenger(Token, s.Client(), false)
+	if err != nil {
+		t.Fatalf("connect to fake telegram: %v", err)
+	}
+
+	return messenger
+}
+
+// AddUpdate queues update for the next getUpdates call, assigning its update ID.
+func (s *Server) AddUpdate(update tgbotapi.Update) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update.UpdateID = s.nextUpdateID
+	s.nextUpdateID++
+	s.updates = append(s.updates, update)
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+
+	return update.UpdateID
+}
+
+// SendText delivers a private message from user; text starting with "/" is sent as a command.
+func (s *Server) SendText(user tgbotapi.User, text string) {
+	s.SendChatText(user, *privateChat(user), text)
+}
+
+// SendChatText delivers a message from user in chat, e.g. a group from Group.
+func (s *Server) SendChatText(user tgbotapi.User, chat tgbotapi.Chat, text string) {
+	msg := &tgbotapi.Message{
+		MessageID: s.messageID(),
+		From:      &user,
+		Date:      int(time.Now().Unix()),
+		Chat:      &chat,
+		Text:      text,
+	}
+
+	if strings.HasPrefix(text, "/") {
+		command, _, _ := strings.Cut(text, " ")
+		msg.Entities = &[]tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}}
+	}
+
+	s.AddUpdate(tgbotapi.Update{Message: msg})
+}
+
+// SendDocument delivers a private message from user with a document the bot can download.
+func (s *Server) SendDocument(user tgbotapi.User, fileName string, data []byte, caption string) {
+	messageID := s.messageID()
+	fileID := fmt.Sprintf("file%d", messageID)
+
+	s.mu.Lock()
+	s.files[fileID] = data
+	s.mu.Unlock()
+
+	s.AddUpdate(tgbotapi.Update{Message: &tgbotapi.Message{
+		MessageID: messageID,
+		From:      &user,
+		Date:      int(time.Now().Unix()),
+		Chat:      privateChat(user),
+		Document:  &tgbotapi.Document{FileID: fileID, FileName: fileName, FileSize: len(data)},
+		Caption:   caption,
+	}})
+}
+
+// SendInlineQuery delivers an inline query typed by user, asking for results from offset.
+func (s *Server) SendInlineQuery(user tgbotapi.User, query, offset string) {
+	s.AddUpdate(tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{
+		ID:     strconv.Itoa(s.messageID()),
+		From:   &user,
+		Query:  query,
+		Offset: offset,
+	}})
+}
+
+// ChooseInlineResult reports that user sent the inline result resultID.
+func (s *Server) ChooseInlineResult(user tgbotapi.User, resultID, query string) {
+	s.AddUpdate(tgbotapi.Update{ChosenInlineResult: &tgbotapi.ChosenInlineResult{
+		ResultID: resultID,
+		From:     &user,
+		Query:    query,
+	}})
+}
+
+// Press delivers a tap on an inline button carrying data, attached to messageID in user's chat.
+func (s *Server) Press(user tgbotapi.User, messageID int, data string) {
+	s.PressInChat(user, *privateChat(user), messageID, data)
+}
+
+// PressInChat delivers a tap by user on an inline button of messageID in chat.
+func (s *Server) PressInChat(user tgbotapi.User, chat tgbotapi.Chat, messageID int, data string) {
+	s.AddUpdate(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      strconv.Itoa(s.messageID()),
+		From:    &user,
+		Message: &tgbotapi.Message{MessageID: messageID, Chat: &chat},
+		Data:    data,
+	}})
+}
+
+// Group registers a supergroup the bot is a member of and returns it.
+func (s *Server) Group(id int64, title string) tgbotapi.Chat {
+	return s.AddChat(tgbotapi.Chat{ID: id, Type: "supergroup", Title: title})
+}
+
+// Channel registers a channel, found by getChat through its ID or @username, and returns it.
+func (s *Server) Channel(id int64, title, username string) tgbotapi.Chat {
+	return s.AddChat(tgbotapi.Chat{ID: id, Type: "channel", Title: title, UserName: username})
+}
+
+// AddChat registers chat for getChat and getChatMember.
+func (s *Server) AddChat(chat tgbotapi.Chat) tgbotapi.Chat {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.chats[chat.ID] = chat
+
+	return chat
+}
+
+// SetMember sets the status getChatMember reports for user in chatID, e.g. "administrator".
+// Users of registered chats are plain members otherwise.
+func (s *Server) SetMember(chatID int64, user tgbotapi.User, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.members[chatID] == nil {
+		s.members[chatID] = make(map[int]string)
+	}
+
+	s.members[chatID][user.ID] = status
+}
+
+type failure struct {
+	code        int
+	description string
+	retryAfter  int
+}
+
+// Block makes messages to chatID fail as if the user blocked the bot.
+func (s *Server) Block(chatID int64) {
+	s.fail(chatID, failure{code: http.StatusForbidden, description: "Forbidden: bot was blocked by the user"})
+}
+
+// Throttle makes messages to chatID fail with 429 and retry_after until Recover is called.
+func (s *Server) Throttle(chatID int64, retryAfter int) {
+	s.fail(chatID, failure{
+		code:        http.StatusTooManyRequests,
+		description: fmt.Sprintf("Too Many Requests: retry after %d", retryAfter),
+		retryAfter:  retryAfter,
+	})
+}
+
+// Recover makes messages to chatID succeed again.
+func (s *Server) Recover(chatID int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.failures, chatID)
+}
+
+func (s *Server) fail(chatID int64, f failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[chatID] = f
+}
+
+// Calls returns the recorded calls to method, or all calls when method is empty.
+func (s *Server) Calls(method string) []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var calls []Call
+
+	for _, call := range s.calls {
+		if method == "" || call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+
+	return calls
+}
+
+// WaitCalls waits until at least n calls to method were made and returns them.
+func (s *Server) WaitCalls(t testing.TB, method string, n int) []Call {
+	t.Helper()
+
+	deadline := time.Now().Add(WaitTimeout)
+
+	for {
+		calls := s.Calls(method)
+		if len(calls) >= n {
+			return calls
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d %s calls after %s, want %d", len(calls), method, WaitTimeout, n)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *Server) messageID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextMessageID
+	s.nextMessageID++
+
+	return id
+}
+
+func privateChat(user tgbotapi.User) *tgbotapi.Chat {
+	return &tgbotapi.Chat{ID: int64(user.ID), Type: "private", UserName: user.UserName, FirstName: user.FirstName}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if fileID, ok := strings.CutPrefix(r.URL.Path, filePathPrefix); ok {
+		s.download(w, r, fileID)
+
+		return
+	}
+
+	token, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !ok || token != Token {
+		writeError(w, http.StatusUnauthorized, "Unauthorized", 0)
+
+		return
+	}
+
+	call, err := parseCall(r, method)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error(), 0)
+
+		return
+	}
+
+	if method != "getUpdates" && method != "getMe" {
+		s.mu.Lock()
+		s.calls = append(s.calls, call)
+		s.mu.Unlock()
+	}
+
+	switch method {
+	case "getMe":
+		writeResult(w, BotUser)
+	case "getUpdates":
+		s.getUpdates(w, r)
+	case "sendMessage", "editMessageText", "sendDocument":
+		s.message(w, call)
+	case "getFile":
+		s.getFile(w, call)
+	case "getChat":
+		s.getChat(w, call)
+	case "getChatMember":
+		s.getChatMember(w, call)
+	case "answerCallbackQuery", "answerInlineQuery", "setWebhook":
+		writeResult(w, true)
+	default:
+		writeError(w, http.StatusNotFound, "Not Found: method not found", 0)
+	}
+}
+
+func (s *Server) message(w http.ResponseWriter, call Call) {
+	chatID := call.ChatID()
+
+	s.mu.Lock()
+	f, failing := s.failures[chatID]
+	s.mu.Unlock()
+
+	if failing {
+		writeError(w, f.code, f.description, f.retryAfter)
+
+		return
+	}
+
+	messageID, _ := strconv.Atoi(call.Params.Get("message_id"))
+	if messageID == 0 {
+		messageID = s.messageID()
+	}
+
+	writeResult(w, tgbotapi.Message{
+		MessageID: messageID,
+		From:      &BotUser,
+		Date:      int(time.Now().Unix()),
+		Chat:      &tgbotapi.Chat{ID: chatID, Type: "private"},
+		Text:      call.Text(),
+	})
+}
+
+// parseCall reads the parameters of a form or multipart request; the bot uploads files as multipart.
+func parseCall(r *http.Request, method string) (Call, error) {
+	call := Call{Method: method}
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err := r.ParseForm()
+		call.Params = r.PostForm
+
+		return call, err
+	}
+
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		return call, err
+	}
+
+	call.Params = url.Values(r.MultipartForm.Value)
+	call.Files = make(map[string][]byte)
+
+	for field, headers := range r.MultipartForm.File {
+		file, err := headers[0].Open()
+		if err != nil {
+			return call, err
+		}
+
+		data, err := io.ReadAll(file)
+		file.Close()
+
+		if err != nil {
+			return call, err
+		}
+
+		call.Files[field] = data
+	}
+
+	return call, nil
+}
+
+// findChat looks a registered chat up by the chat_id parameter, an ID or an @username.
+func (s *Server) findChat(call Call) (tgbotapi.Chat, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	param := call.Params.Get("chat_id")
+
+	for _, chat := range s.chats {
+		if strconv.FormatInt(chat.ID, 10) == param || (chat.UserName != "" && "@"+chat.UserName == param) {
+			return chat, true
+		}
+	}
+
+	return tgbotapi.Chat{}, false
+}
+
+func (s *Server) getChat(w http.ResponseWriter, call Call) {
+	chat, ok := s.findChat(call)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad Request: chat not found", 0)
+
+		return
+	}
+
+	writeResult(w, chat)
+}
+
+func (s *Server) getChatMember(w http.ResponseWriter, call Call) {
+	chat, ok := s.findChat(call)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad Request: chat not found", 0)
+
+		return
+	}
+
+	userID, _ := strconv.Atoi(call.Params.Get("user_id"))
+
+	s.mu.Lock()
+	status, ok := s.members[chat.ID][userID]
+	s.mu.Unlock()
+
+	if !ok {
+		status = "member"
+	}
+
+	writeResult(w, tgbotapi.ChatMember{User: &tgbotapi.User{ID: userID}, Status: status})
+}
+
+func (s *Server) getFile(w http.ResponseWriter, call Call) {
+	fileID := call.Params.Get("file_id")
+
+	s.mu.Lock()
+	data, ok := s.files[fileID]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusBadRequest, "Bad Request: invalid file_id", 0)
+
+		return
+	}
+
+	writeResult(w, tgbotapi.File{FileID: fileID, FileSize: len(data), FilePath: fileID})
+}
+
+func (s *Server) download(w http.ResponseWriter, r *http.Request, fileID string) {
+	s.mu.Lock()
+	data, ok := s.files[fileID]
+	s.mu.Unlock()
+
+	if !ok {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	_, _ = w.Write(data)
+}
+
+// getUpdates returns updates from the requested offset, long-polling for at most maxPollWait.
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request) {
+	offset, _ := strconv.Atoi(r.PostForm.Get("offset"))
+	timeout, _ := strconv.Atoi(r.PostForm.Get("timeout"))
+	deadline := time.After(min(time.Duration(timeout)*time.Second, maxPollWait))
+
+	for {
+		s.mu.Lock()
+
+		var pending []tgbotapi.Update
+
+		for _, update := range s.updates {
+			if update.UpdateID >= offset {
+				pending = append(pending, update)
+			}
+		}
+
+		changed := s.changed
+		s.mu.Unlock()
+
+		if len(pending) > 0 || timeout == 0 {
+			writeResult(w, pending)
+
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-deadline:
+			writeResult(w, []tgbotapi.Update{})
+
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), 0)
+
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tgbotapi.APIResponse{Ok: true, Result: encoded})
+}
+
+func writeError(w http.ResponseWriter, code int, description string, retryAfter int) {
+	response := tgbotapi.APIResponse{ErrorCode: code, Description: description}
+	if retryAfter > 0 {
+		response.Parameters = &tgbotapi.ResponseParameters{RetryAfter: retryAfter}
+	}
+
+	writeJSON(w, code, response)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// redirectTransport rewrites requests for any host to target.
+type redirectTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	r.Host = t.target.Host
+
+	return t.next.RoundTrip(r)
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+const (
+	dateLayout      = "Monday, January 2, 2006"
+	shortDateLayout = "Jan 2, 2006"
+)
+
+// userLocation returns the time zone the user's dates are rendered in, UTC unless they set one.
+func (b *Bot) userLocation(userID int64) *time.Location {
+	user, err := b.dbManager.GetUser(userID)
+	if err != nil {
+		slog.Debug("Falling back to UTC for user", "userID", userID, "err", err)
+
+		return time.UTC
+	}
+
+	return user.Location()
+}
+
+// formatAirTime renders an air time in loc with its clock time. Date-only values
+// (exact is false) keep the network's calendar date, which has no zone to convert from.
+func formatAirTime(t time.Time, exact bool, loc *time.Location, layout string) string {
+	if !exact {
+		return t.Format(layout)
+	}
+
+	return t.In(loc).Format(layout + " at 15:04 MST")
+}
+
+func formatEpisodeAirTime(episode *models.Episode, loc *time.Location, layout string) string {
+	return formatAirTime(episode.AirTime(), episode.HasAirTime(), loc, layout)
+}
+
+func (b *Bot) handleTimezoneCommand(message *tgbotapi.Message) {
+	userID := int64(subscriberID(message.Chat))
+	name := strings.TrimSpace(message.CommandArguments())
+
+	if name == "" {
+		loc := b.userLocation(userID)
+		b.sendMessage(message.Chat.ID, fmt.Sprintf(
+			"Your time zone is *%s* (local time %s).\n\nSet it with /timezone followed by a zone name, e.g. /timezone Europe/Berlin",
+			loc.String(),
+			time.Now().In(loc).Format("15:04"),
+		))
+
+		return
+	}
+
+	if !b.canManage(message.Chat, message.From) {
+		b.sendMessage(message.Chat.ID, "Only group admins can change the time zone.")
+
+		return
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "Local" {
+		b.sendMessage(message.Chat.ID, fmt.Sprintf(
+			"Unknown time zone %q. Use a name from the tz database, e.g. America/New_York or Asia/Tokyo.", name))
+
+		return
+	}
+
+	if err = b.dbManager.SetUserTimezone(userID, loc.String()); err != nil {
+		slog.Error("Error setting user time zone", "userID", userID, "err", err)
+		b.sendMessage(message.Chat.ID, "An error occurred while saving your time zone.")
+
+		return
+	}
+
+	b.sendMessage(message.Chat.ID, fmt.Sprintf("Time zone set to *%s*. Local time is %s.",
+		loc.String(), time.Now().In(loc).Format("15:04")))
+}
@@ -0,0 +1,354 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+
+	"github.com/dkhalizov/shows/clients"
+	"github.com/dkhalizov/shows/internal/models"
+	"github.com/dkhalizov/shows/internal/transfer"
+)
+
+const (
+	maxImportSize    = 1 << 20
+	maxImportEntries = 500
+
+	// importTimeout bounds a whole import; every unmatched title costs a search on each provider.
+	importTimeout = 10 * time.Minute
+
+	// maxReportedTitles keeps the import report short; the rest are only counted.
+	maxReportedTitles = 30
+)
+
+// importReport is the outcome of importing a file's entries.
+type importReport struct {
+	followed  []string
+	already   int
+	overLimit int
+	unmatched []transfer.Entry
+}
+
+func (b *Bot) handleExportCommand(message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+
+	shows, err := b.dbManager.GetUserShows(subscriberID(message.Chat))
+	if err != nil {
+		slog.Error("Error getting shows", "err", err)
+		b.sendMessage(chatID, "An error occurred while exporting your shows.")
+
+		return
+	}
+
+	if len(shows) == 0 {
+		b.sendMessage(chatID, "You're not following any shows yet, so there is nothing to export.")
+
+		return
+	}
+
+	var (
+		buf  bytes.Buffer
+		name string
+	)
+
+	switch format := strings.ToLower(strings.TrimSpace(message.CommandArguments())); format {
+	case "", transfer.FormatJSON:
+		name, err = "shows.json", transfer.WriteJSON(&buf, shows, time.Now())
+	case transfer.FormatCSV:
+		name, err = "shows.csv", transfer.WriteCSV(&buf, shows)
+	default:
+		b.sendMessage(chatID, "Unknown export format. Use /export json or /export csv.")
+
+		return
+	}
+
+	if err != nil {
+		slog.Error("Error writing export", "err", err)
+		b.sendMessage(chatID, "An error occurred while exporting your shows.")
+
+		return
+	}
+
+	doc := tgbotapi.NewDocumentUpload(chatID, tgbotapi.FileBytes{Name: name, Bytes: buf.Bytes()})
+	doc.Caption = fmt.Sprintf("Your %d followed shows. Send this file back to the bot to import them.", len(shows))
+
+	if _, err = b.sender.sendNow(chatID, doc, laneInteractive); err != nil {
+		slog.Error("Error sending export", "err", err)
+		b.deactivateIfUnreachable(chatID, err)
+	}
+}
+
+func (b *Bot) handleImportCommand(message *tgbotapi.Message) {
+	if !message.Chat.IsPrivate() {
+		b.sendMessage(message.Chat.ID, "Imports work in a private chat with me. Follow shows for this group with /search.")
+
+		return
+	}
+
+	b.sendMessage(message.Chat.ID, `📥 *Import Shows*
+
+Send me a file and I'll follow the shows in it:
+
+• A JSON or CSV file from /export
+• An IMDb watchlist export (CSV)
+• A Trakt watchlist, watched or collection export (JSON)
+
+Shows you already follow are kept. Titles I can't match are listed when the import is done.`)
+}
+
+// handleDocument imports an uploaded file. Matching titles against the providers can take
+// minutes, so it runs in the background and reports back when done.
+func (b *Bot) handleDocument(ctx context.Context, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	document := message.Document
+
+	if document.FileSize > maxImportSize {
+		b.sendMessage(chatID, "That file is too large to import. Files up to 1 MB are accepted.")
+
+		return
+	}
+
+	data, err := b.api.DownloadFile(ctx, document.FileID, maxImportSize)
+	if errors.Is(err, errFileTooLarge) {
+		b.sendMessage(chatID, "That file is too large to import. Files up to 1 MB are accepted.")
+
+		return
+	}
+
+	if err != nil {
+		slog.Error("Error downloading import file", "err", err)
+		b.sendMessage(chatID, "An error occurred while reading your file.")
+
+		return
+	}
+
+	imported, err := transfer.Parse(data)
+	if err != nil {
+		slog.Info("Unreadable import file", "name", document.FileName, "err", err)
+		b.sendMessage(chatID, "I couldn't read that file. Type /import to see which files I can import.")
+
+		return
+	}
+
+	if len(imported.Entries) == 0 {
+		b.sendMessage(chatID, "I didn't find any TV shows in that file.")
+
+		return
+	}
+
+	if len(imported.Entries) > maxImportEntries {
+		b.sendMessage(chatID, fmt.Sprintf("That file lists %d shows. Up to %d can be imported at once.", len(imported.Entries), maxImportEntries))
+
+		return
+	}
+
+	b.sendMessage(chatID, fmt.Sprintf("Importing %d shows. This can take a few minutes, I'll let you know when it's done.", len(imported.Entries)))
+
+	userID := subscriberID(message.Chat)
+
+	b.goTracked(func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), importTimeout)
+		defer cancel()
+
+		report := b.importShows(ctx, userID, imported.Entries)
+		b.sendMessage(chatID, report.message(imported, b.config.Bot.MaxFollowedShows))
+	})
+}
+
+// importShows follows the show of every entry it can match, up to Bot.MaxFollowedShows.
+func (b *Bot) importShows(ctx context.Context, userID int, entries []transfer.Entry) importReport {
+	var report importReport
+
+	following, err := b.dbManager.GetUserShows(userID)
+	if err != nil {
+		slog.Error("Error getting shows", "err", err)
+	}
+
+	count := len(following)
+
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			report.unmatched = append(report.unmatched, entry)
+
+			continue
+		}
+
+		show, err := b.resolveImport(ctx, entry)
+		if err != nil {
+			slog.Error("Error resolving imported show", "title", entry.Title, "err", err)
+		}
+
+		if show == nil {
+			report.unmatched = append(report.unmatched, entry)
+
+			continue
+		}
+
+		if followed, err := b.dbManager.IsUserFollowingShow(userID, show.ID); err == nil && followed {
+			report.already++
+
+			continue
+		}
+
+		if limit := b.config.Bot.MaxFollowedShows; limit > 0 && count >= limit {
+			report.overLimit++
+
+			continue
+		}
+
+		if err = b.dbManager.FollowShow(userID, show.ID); err != nil {
+			slog.Error("Error following imported show", "showID", show.ID, "err", err)
+			report.unmatched = append(report.unmatched, entry)
+
+			continue
+		}
+
+		count++
+		report.followed = append(report.followed, show.Name)
+
+		if err = b.storeAllEpisodes(ctx, show); err != nil {
+			slog.Error("Error storing episodes", "showID", show.ID, "err", err)
+		}
+	}
+
+	return report
+}
+
+// resolveImport finds the show an entry names: by its IMDb ID when the file has one, then directly at
+// its provider when the file says which, otherwise by searching every provider for the title. StoreShow's IMDb matching then maps it onto
+// the show already stored, if any. A nil show means no match.
+func (b *Bot) resolveImport(ctx context.Context, entry transfer.Entry) (*models.Show, error) {
+	if entry.IMDbID != "" {
+		show, err := b.showByIMDbID(ctx, entry.IMDbID)
+		if err == nil {
+			return show, nil
+		}
+
+		if !errors.Is(err, clients.ErrNotFound) {
+			slog.Warn("Imported show not found by IMDb ID, trying other lookups", "imdbID", entry.IMDbID, "err", err)
+		}
+	}
+
+	if client, ok := b.apiClients[entry.Provider]; ok && entry.ProviderID != "" {
+		show, err := client.GetShowDetails(ctx, entry.ProviderID)
+		if err == nil {
+			return b.storeImportedShow(show)
+		}
+
+		slog.Warn("Imported show not found at its provider, searching by title",
+			"provider", entry.Provider, "providerID", entry.ProviderID, "err", err)
+	}
+
+	if entry.Title == "" {
+		return nil, nil
+	}
+
+	var candidates []models.Show
+
+	for _, provider := range slices.Sorted(maps.Keys(b.apiClients)) {
+		results, err := b.apiClients[provider].SearchShows(ctx, entry.Title)
+		if err != nil {
+			slog.Error("Error searching shows", "provider", provider, "err", err)
+
+			continue
+		}
+
+		candidates = append(candidates, results...)
+	}
+
+	match := matchImport(entry, candidates)
+	if match == nil {
+		return nil, nil
+	}
+
+	return b.storeImportedShow(match)
+}
+
+func (b *Bot) storeImportedShow(show *models.Show) (*models.Show, error) {
+	showID, err := b.dbManager.StoreShow(show)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.dbManager.GetShow(showID)
+}
+
+// matchImport picks the search result with the entry's IMDb ID, falling back to an exact title
+// match in the entry's year when no result carries that ID.
+func matchImport(entry transfer.Entry, candidates []models.Show) *models.Show {
+	if entry.IMDbID != "" {
+		for i := range candidates {
+			if candidates[i].IMDbID == entry.IMDbID {
+				return &candidates[i]
+			}
+		}
+	}
+
+	for i := range candidates {
+		if entry.IMDbID != "" && candidates[i].IMDbID != "" {
+			continue
+		}
+
+		if !strings.EqualFold(strings.TrimSpace(candidates[i].Name), entry.Title) {
+			continue
+		}
+
+		if entry.Year == 0 || candidates[i].FirstAirDate.Year() == entry.Year {
+			return &candidates[i]
+		}
+	}
+
+	return nil
+}
+
+func (r importReport) message(imported *transfer.Import, limit int) string {
+	text := "📥 *Import finished*\n"
+
+	if len(r.followed) > 0 {
+		text += fmt.Sprintf("\nNewly followed (%d): %s", len(r.followed), strings.Join(r.followed, ", "))
+	} else {
+		text += "\nNo new shows were followed."
+	}
+
+	if r.already > 0 {
+		text += fmt.Sprintf("\nAlready following: %d", r.already)
+	}
+
+	if r.overLimit > 0 {
+		text += fmt.Sprintf("\nNot followed, over the limit of %d shows: %d", limit, r.overLimit)
+	}
+
+	if imported.Skipped > 0 {
+		text += fmt.Sprintf("\nSkipped, not TV shows: %d", imported.Skipped)
+	}
+
+	// JSON files have no meaningful line numbers, so their entries are numbered instead.
+	position := "line"
+	if imported.Format == transfer.FormatJSON || imported.Format == transfer.FormatTrakt {
+		position = "entry"
+	}
+
+	if len(r.unmatched) > 0 {
+		text += fmt.Sprintf("\n\nNot matched (%d):", len(r.unmatched))
+
+		for i, entry := range r.unmatched {
+			if i == maxReportedTitles {
+				text += fmt.Sprintf("\n…and %d more", len(r.unmatched)-maxReportedTitles)
+
+				break
+			}
+
+			text += fmt.Sprintf("\n• %s (%s %d)", entry.Label(), position, entry.Line)
+		}
+	}
+
+	return text
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api"
+)
+
+const (
+	secretTokenHeader  = "X-Telegram-Bot-Api-Secret-Token"
+	defaultWebhookPath = "/webhook"
+	maxUpdateSize      = 1 << 20
+)
+
+// startWebhook registers the webhook with Telegram (when a public URL is configured)
+// and serves updates, alongside the other routes, on the embedded HTTP server until ctx is cancelled.
+func (b *Bot) startWebhook(ctx, handlerCtx context.Context) error {
+	path := defaultWebhookPath
+
+	if b.config.Bot.WebhookURL != "" {
+		webhookURL, err := url.Parse(b.config.Bot.WebhookURL)
+		if err != nil {
+			return fmt.Errorf("invalid webhook URL: %w", err)
+		}
+
+		if webhookURL.Path != "" {
+			path = webhookURL.Path
+		}
+
+		if err = b.registerWebhook(webhookURL); err != nil {
+			return err
+		}
+	} else {
+		slog.Warn("Webhook URL is not configured, skipping registration with Telegram")
+	}
+
+	mux := b.newServeMux()
+	mux.Handle(path, b.webhookHandler(handlerCtx))
+
+	slog.Info("Listening for webhook updates", "port", b.config.Server.Port, "path", path)
+
+	return b.serveHTTP(ctx, mux)
+}
+
+func (b *Bot) registerWebhook(webhookURL *url.URL) error {
+	registrar, ok := b.api.(webhookRegistrar)
+	if !ok {
+		return errors.New("failed to set webhook: messenger cannot register webhooks")
+	}
+
+	if err := registrar.SetWebhook(webhookURL.String(), b.config.Bot.WebhookSecret); err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+
+	slog.Info("Webhook registered", "url", webhookURL.Redacted())
+
+	return nil
+}
+
+func (b *Bot) webhookHandler(ctx context.Context) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		b.handleWebhook(ctx, w, r)
+	}
+}
+
+func (b *Bot) handleWebhook(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	if !b.validWebhookSecret(r.Header.Get(secretTokenHeader)) {
+		slog.Warn("Rejected webhook request with invalid secret token", "remote", r.RemoteAddr)
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+		return
+	}
+
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateSize)).Decode(&update); err != nil {
+		slog.Error("Failed to decode webhook update", "err", err)
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+
+		return
+	}
+
+	b.goTracked(func() { b.processUpdate(ctx, update) })
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (b *Bot) validWebhookSecret(token string) bool {
+	secret := b.config.Bot.WebhookSecret
+	if secret == "" {
+		return true
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -28,12 +29,33 @@ type Server struct {
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
 }
 
+// Calendar serves each user's episodes as an iCalendar feed from the embedded HTTP server.
+type Calendar struct {
+	Enabled  bool   `yaml:"enabled"`
+	BaseURL  string `yaml:"base_url"`  // public URL of the embedded HTTP server, used in feed links
+	PastDays int    `yaml:"past_days"` // how long aired episodes stay in the feed
+}
+
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
 type Bot struct {
+	Mode                         string        `yaml:"mode"`           // polling or webhook
+	WebhookURL                   string        `yaml:"webhook_url"`    // public URL registered with Telegram
+	WebhookSecret                string        `yaml:"webhook_secret"` // X-Telegram-Bot-Api-Secret-Token value
 	NotificationEnabled          bool          `yaml:"notification_enabled"`
 	CheckInterval                time.Duration `yaml:"check_interval"`
 	MaxResults                   int           `yaml:"max_results"`
 	MaxFollowedShows             int           `yaml:"max_followed_shows"`
 	EpisodeNotificationThreshold time.Duration `yaml:"episode_notification_threshold"`
+	UpdateTimeout                time.Duration `yaml:"update_timeout"`        // deadline for handling a single Telegram update
+	RefreshTimeout               time.Duration `yaml:"refresh_timeout"`       // deadline for refreshing a single show from its provider
+	ShowRefreshInterval          time.Duration `yaml:"show_refresh_interval"` // how often show details and status are re-fetched
+	ReminderInterval             time.Duration `yaml:"reminder_interval"`     // how often stored episodes are checked for due reminders
+	SendRateLimit                int           `yaml:"send_rate_limit"`       // messages per second to Telegram across all chats
+	ChatSendRateLimit            int           `yaml:"chat_send_rate_limit"`  // messages per second to a single chat
 }
 
 type Database struct {
@@ -64,16 +86,28 @@ type APIClients struct {
 }
 
 type Development struct {
-	Enabled   bool `yaml:"enabled"`
-	MockAPIs  bool `yaml:"mock_apis"`
-	DebugMode bool `yaml:"debug_mode"`
+	Enabled       bool      `yaml:"enabled"`
+	MockAPIs      bool      `yaml:"mock_apis"`      // serve shows from fixtures instead of provider APIs
+	FixturesDir   string    `yaml:"fixtures_dir"`   // mock fixtures, the built-in set when empty
+	MockNow       time.Time `yaml:"mock_now"`       // pins the date relative fixture episodes count from
+	HTTPRecording string    `yaml:"http_recording"` // record or replay provider HTTP responses
+	RecordingsDir string    `yaml:"recordings_dir"`
+	DebugMode     bool      `yaml:"debug_mode"`
 }
 
+const (
+	RecordingRecord = "record"
+	RecordingReplay = "replay"
+)
+
 type Config struct {
 	TelegramToken string            `yaml:"telegram_token"`
 	APIKeys       map[string]string `yaml:"api_keys"`
 
-	Bot Bot `yaml:"bot"`
+	Bot    Bot    `yaml:"bot"`
+	Server Server `yaml:"server"`
+
+	Calendar Calendar `yaml:"calendar"`
 
 	Logging  Logging  `yaml:"logging"`
 	Database Database `yaml:"database"`
@@ -88,11 +122,25 @@ func DefaultConfig() Config {
 		APIKeys: make(map[string]string),
 	}
 
+	cfg.Bot.Mode = ModePolling
 	cfg.Bot.NotificationEnabled = true
 	cfg.Bot.CheckInterval = 6 * time.Hour
 	cfg.Bot.MaxResults = 5
 	cfg.Bot.MaxFollowedShows = 100
 	cfg.Bot.EpisodeNotificationThreshold = 7 * 24 * time.Hour
+	cfg.Bot.UpdateTimeout = 30 * time.Second
+	cfg.Bot.RefreshTimeout = 2 * time.Minute
+	cfg.Bot.ShowRefreshInterval = 24 * time.Hour
+	cfg.Bot.ReminderInterval = 5 * time.Minute
+	cfg.Bot.SendRateLimit = 30
+	cfg.Bot.ChatSendRateLimit = 1
+
+	cfg.Server.Port = 8080
+	cfg.Server.ReadTimeout = 10 * time.Second
+	cfg.Server.WriteTimeout = 10 * time.Second
+	cfg.Server.ShutdownTimeout = 30 * time.Second
+
+	cfg.Calendar.PastDays = 30
 
 	cfg.Logging.Level = "info"
 	cfg.Logging.MaxSize = 100
@@ -193,6 +241,29 @@ func (c *Config) loadFromEnv() {
 		c.APIKeys["tmdb"] = tmdbKey
 	}
 
+	if mode := os.Getenv("BOT_MODE"); mode != "" {
+		c.Bot.Mode = mode
+	}
+
+	if webhookURL := os.Getenv("WEBHOOK_URL"); webhookURL != "" {
+		c.Bot.WebhookURL = webhookURL
+	}
+
+	if webhookSecret := os.Getenv("WEBHOOK_SECRET"); webhookSecret != "" {
+		c.Bot.WebhookSecret = webhookSecret
+	}
+
+	if port := os.Getenv("PORT"); port != "" {
+		if p, err := strconv.Atoi(port); err == nil {
+			c.Server.Port = p
+		}
+	}
+
+	if calendarURL := os.Getenv("CALENDAR_BASE_URL"); calendarURL != "" {
+		c.Calendar.Enabled = true
+		c.Calendar.BaseURL = calendarURL
+	}
+
 	if interval := os.Getenv("BOT_CHECK_INTERVAL"); interval != "" {
 		if duration, err := time.ParseDuration(interval); err == nil {
 			c.Bot.CheckInterval = duration
@@ -214,6 +285,10 @@ func (c *Config) loadFromEnv() {
 	if devMode := os.Getenv("DEV_MODE"); devMode != "" {
 		c.Development.Enabled = devMode == "true" || devMode == "1" || devMode == "yes"
 	}
+
+	if mockAPIs := os.Getenv("MOCK_APIS"); mockAPIs != "" {
+		c.Development.MockAPIs = mockAPIs == "true" || mockAPIs == "1" || mockAPIs == "yes"
+	}
 }
 
 func (c *Config) validate() error {
@@ -225,6 +300,42 @@ func (c *Config) validate() error {
 		return errors.New("database URL is required")
 	}
 
+	switch c.Bot.Mode {
+	case "", ModePolling:
+	case ModeWebhook:
+		if c.Server.Port <= 0 {
+			return errors.New("server port is required in webhook mode")
+		}
+
+		// Without a secret anyone who finds the public URL could forge updates; only
+		// unregistered local replay may skip it.
+		if c.Bot.WebhookURL != "" && c.Bot.WebhookSecret == "" {
+			return errors.New("webhook_secret is required when webhook_url is set")
+		}
+	default:
+		return fmt.Errorf("unknown bot mode %q", c.Bot.Mode)
+	}
+
+	if c.Calendar.Enabled {
+		if c.Calendar.BaseURL == "" {
+			return errors.New("calendar base_url is required to serve calendar feeds")
+		}
+
+		if c.Server.Port <= 0 {
+			return errors.New("server port is required to serve calendar feeds")
+		}
+	}
+
+	switch c.Development.HTTPRecording {
+	case "":
+	case RecordingRecord, RecordingReplay:
+		if c.Development.RecordingsDir == "" {
+			return errors.New("recordings_dir is required to record or replay provider responses")
+		}
+	default:
+		return fmt.Errorf("unknown http_recording mode %q", c.Development.HTTPRecording)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,120 @@
+// Package dbtest is a conformance suite for bot.Operations implementations.
+//
+// Backends are exercised through the same scenarios so that SQLite, Postgres
+// and the in-memory store behave identically:
+//
+//	func TestSQLite(t *testing.T) {
+//		dbtest.Run(t, dbtest.OpenSQLite)
+//	}
+//
+//	func TestMemory(t *testing.T) {
+//		dbtest.Run(t, dbtest.OpenMemory)
+//	}
+package dbtest
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dkhalizov/shows/internal/bot"
+	"github.com/dkhalizov/shows/internal/config"
+	"github.com/dkhalizov/shows/internal/database"
+	"github.com/dkhalizov/shows/internal/database/memory"
+)
+
+// PostgresURLEnv names the variable holding a disposable Postgres database for OpenPostgres.
+const PostgresURLEnv = "TEST_DATABASE_URL"
+
+// Opener returns a freshly initialised, empty store.
+type Opener func(t *testing.T) bot.Operations
+
+// OpenSQLite opens an initialised in-memory SQLite Manager.
+func OpenSQLite(t *testing.T) bot.Operations {
+	t.Helper()
+
+	return openManager(t, config.Database{DatabaseURL: ":memory:"})
+}
+
+// OpenMemory returns an empty in-memory store.
+func OpenMemory(t *testing.T) bot.Operations {
+	t.Helper()
+
+	return memory.New()
+}
+
+// OpenPostgres opens the database named by TEST_DATABASE_URL, resetting its schema first.
+// The test is skipped when the variable is not set.
+func OpenPostgres(t *testing.T) bot.Operations {
+	t.Helper()
+
+	url := os.Getenv(PostgresURLEnv)
+	if url == "" {
+		t.Skipf("%s is not set", PostgresURLEnv)
+	}
+
+	manager, err := database.NewManager(config.Database{DatabaseURL: url})
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+
+	if _, err = manager.MigrateDown(int(^uint(0) >> 1)); err != nil {
+		t.Fatalf("reset schema: %v", err)
+	}
+
+	if err = manager.Close(); err != nil {
+		t.Fatalf("close postgres: %v", err)
+	}
+
+	return openManager(t, config.Database{DatabaseURL: url})
+}
+
+func openManager(t *testing.T, cfg config.Database) bot.Operations {
+	t.Helper()
+
+	manager, err := database.NewManager(cfg)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	if err = manager.Init(); err != nil {
+		t.Fatalf("init database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := manager.Close(); err != nil {
+			t.Errorf("close database: %v", err)
+		}
+	})
+
+	return manager
+}
+
+// Run executes every conformance scenario against stores produced by open.
+func Run(t *testing.T, open Opener) {
+	t.Helper()
+
+	for _, tc := range scenarios {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, open(t))
+		})
+	}
+}
+
+type scenario struct {
+	name string
+	run  func(t *testing.T, ops bot.Operations)
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// day returns a UTC midnight offset from today, the precision providers report air dates in.
+func day(offset int) time.Time {
+	return time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, offset)
+}
@@ -0,0 +1,1238 @@
+package dbtest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dkhalizov/shows/internal/bot"
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+var scenarios = []scenario{
+	{"StoreShowGeneratesID", testStoreShowGeneratesID},
+	{"StoreShowDedupesOnIMDb", testStoreShowDedupesOnIMDb},
+	{"StoreShowDedupesOnProvider", testStoreShowDedupesOnProvider},
+	{"StoreShowBackfillsIMDb", testStoreShowBackfillsIMDb},
+	{"GetShowMissing", testGetShowMissing},
+	{"UpdateShowDetails", testUpdateShowDetails},
+	{"UpdateShowDetailsKeepsStatus", testUpdateShowDetailsKeepsStatus},
+	{"StoreUserKeepsTimezone", testStoreUserKeepsTimezone},
+	{"SetUserTimezoneMissingUser", testSetUserTimezoneMissingUser},
+	{"InactiveUsersSkipped", testInactiveUsersSkipped},
+	{"FollowUnfollow", testFollowUnfollow},
+	{"FollowShowIdempotent", testFollowShowIdempotent},
+	{"FollowRequiresUserAndShow", testFollowRequiresUserAndShow},
+	{"GetUserShowsOrderedByName", testGetUserShowsOrderedByName},
+	{"GetAllFollowedShowsDistinct", testGetAllFollowedShowsDistinct},
+	{"GetUsersToNotifySkipsNotified", testGetUsersToNotifySkipsNotified},
+	{"GetUsersToNotifyLeadTimes", testGetUsersToNotifyLeadTimes},
+	{"GetUsersToNotifySkipsMuted", testGetUsersToNotifySkipsMuted},
+	{"GetUsersToNotifyQuietHours", testGetUsersToNotifyQuietHours},
+	{"UserSettingsRoundTrip", testUserSettingsRoundTrip},
+	{"DigestSubscribers", testDigestSubscribers},
+	{"DigestEpisodesAndRecording", testDigestEpisodesAndRecording},
+	{"EnqueueNotificationAtomic", testEnqueueNotificationAtomic},
+	{"OutboxRetryAndFinalStatus", testOutboxRetryAndFinalStatus},
+	{"RecordNotificationUnique", testRecordNotificationUnique},
+	{"StoreEpisodeIdempotent", testStoreEpisodeIdempotent},
+	{"StoreEpisodeRecordsChanges", testStoreEpisodeRecordsChanges},
+	{"StoreEpisodeAirStamp", testStoreEpisodeAirStamp},
+	{"GetShowFollowers", testGetShowFollowers},
+	{"GetShowFollowersSkipsInactiveAndMuted", testGetShowFollowersSkipsInactiveAndMuted},
+	{"ClearNotifications", testClearNotifications},
+	{"GetNextEpisode", testGetNextEpisode},
+	{"GetUpcomingEpisodesForUser", testGetUpcomingEpisodesForUser},
+	{"UpcomingEpisodesUseAirStamp", testUpcomingEpisodesUseAirStamp},
+	{"GetEpisodesForShowOrdered", testGetEpisodesForShowOrdered},
+	{"GetEpisodeLoadsShow", testGetEpisodeLoadsShow},
+	{"WatchedEpisodes", testWatchedEpisodes},
+	{"WatchedEpisodesSuppressReminders", testWatchedEpisodesSuppressReminders},
+	{"CalendarToken", testCalendarToken},
+	{"GetCalendarEpisodes", testGetCalendarEpisodes},
+	{"GetShowByIMDbID", testGetShowByIMDbID},
+	{"NotifyChat", testNotifyChat},
+}
+
+func storeShow(t *testing.T, ops bot.Operations, provider, providerID, name, imdbID string) string {
+	t.Helper()
+
+	id, err := ops.StoreShow(&models.Show{Name: name, Provider: provider, ProviderID: providerID, IMDbID: imdbID})
+	must(t, err)
+
+	return id
+}
+
+// defaultLead stands in for bot.episode_notification_threshold.
+const defaultLead = 7 * 24 * time.Hour
+
+func storeEpisodeAiring(t *testing.T, ops bot.Operations, showID, providerID string, airDate time.Time) *models.Episode {
+	t.Helper()
+
+	episode := &models.Episode{ShowID: showID, Name: "Pilot", AirDate: airDate, Provider: "tvmaze", ProviderID: providerID}
+	_, err := ops.StoreEpisode(episode)
+	must(t, err)
+
+	return episode
+}
+
+func reminderUsers(reminders []models.Reminder) []int64 {
+	userIDs := make([]int64, len(reminders))
+	for i, reminder := range reminders {
+		userIDs[i] = reminder.UserID
+	}
+
+	return userIDs
+}
+
+func storeUser(t *testing.T, ops bot.Operations, id int64) {
+	t.Helper()
+
+	must(t, ops.StoreUser(models.User{ID: id, Username: "user"}))
+}
+
+func testStoreShowGeneratesID(t *testing.T, ops bot.Operations) {
+	id := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	if id != "tvmaze_1" {
+		t.Fatalf("id = %q, want tvmaze_1", id)
+	}
+
+	show, err := ops.GetShow(id)
+	must(t, err)
+
+	if show.Name != "Show" || show.Provider != "tvmaze" || show.ProviderID != "1" {
+		t.Fatalf("unexpected show %+v", show)
+	}
+}
+
+func testStoreShowDedupesOnIMDb(t *testing.T, ops bot.Operations) {
+	first := storeShow(t, ops, "tvmaze", "1", "Show", "tt0000001")
+	second := storeShow(t, ops, "tmdb", "99", "Show", "tt0000001")
+
+	if first != second {
+		t.Fatalf("same IMDb ID stored twice: %q and %q", first, second)
+	}
+}
+
+func testStoreShowDedupesOnProvider(t *testing.T, ops bot.Operations) {
+	first := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	second := storeShow(t, ops, "tvmaze", "1", "Show renamed", "")
+
+	if first != second {
+		t.Fatalf("same provider ID stored twice: %q and %q", first, second)
+	}
+}
+
+func testStoreShowBackfillsIMDb(t *testing.T, ops bot.Operations) {
+	id := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	storeShow(t, ops, "tvmaze", "1", "Show", "tt0000001")
+
+	show, err := ops.GetShow(id)
+	must(t, err)
+
+	if show.IMDbID != "tt0000001" {
+		t.Fatalf("IMDb ID = %q, want backfilled tt0000001", show.IMDbID)
+	}
+}
+
+func testGetShowMissing(t *testing.T, ops bot.Operations) {
+	if _, err := ops.GetShow("missing"); err == nil {
+		t.Fatal("expected an error for a missing show")
+	}
+}
+
+func testUpdateShowDetails(t *testing.T, ops bot.Operations) {
+	id, err := ops.StoreShow(&models.Show{
+		Name: "Show", Provider: "tvmaze", ProviderID: "1", Status: "Running", Overview: "Old overview", PosterURL: "poster",
+	})
+	must(t, err)
+
+	oldStatus, err := ops.UpdateShowDetails(&models.Show{ID: id, Name: "Show", Status: "Ended", IMDbID: "tt0000001"})
+	must(t, err)
+
+	if oldStatus != "Running" {
+		t.Fatalf("old status = %q, want Running", oldStatus)
+	}
+
+	show, err := ops.GetShow(id)
+	must(t, err)
+
+	if show.Status != "Ended" || show.IMDbID != "tt0000001" {
+		t.Fatalf("details not updated: %+v", show)
+	}
+
+	if show.Overview != "Old overview" || show.PosterURL != "poster" {
+		t.Fatalf("empty details overwrote stored ones: %+v", show)
+	}
+
+	if show.RefreshedAt.IsZero() {
+		t.Fatal("RefreshedAt not set")
+	}
+
+	if _, err = ops.UpdateShowDetails(&models.Show{ID: "missing", Status: "Ended"}); err == nil {
+		t.Fatal("expected an error for a missing show")
+	}
+}
+
+func testUpdateShowDetailsKeepsStatus(t *testing.T, ops bot.Operations) {
+	id, err := ops.StoreShow(&models.Show{Name: "Show", Provider: "tvmaze", ProviderID: "1", Status: "Running"})
+	must(t, err)
+
+	oldStatus, err := ops.UpdateShowDetails(&models.Show{ID: id, Name: "Show"})
+	must(t, err)
+
+	show, err := ops.GetShow(id)
+	must(t, err)
+
+	if oldStatus != "Running" || show.Status != "Running" {
+		t.Fatalf("old status %q, status %q after a refresh without status, want Running for both", oldStatus, show.Status)
+	}
+}
+
+func testStoreUserKeepsTimezone(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	must(t, ops.SetUserTimezone(1, "Asia/Tokyo"))
+	must(t, ops.StoreUser(models.User{ID: 1, Username: "renamed"}))
+
+	user, err := ops.GetUser(1)
+	must(t, err)
+
+	if user.Username != "renamed" || user.Timezone != "Asia/Tokyo" {
+		t.Fatalf("user = %+v, want renamed with Asia/Tokyo", user)
+	}
+
+	if user.Location().String() != "Asia/Tokyo" {
+		t.Fatalf("Location = %s, want Asia/Tokyo", user.Location())
+	}
+}
+
+func testSetUserTimezoneMissingUser(t *testing.T, ops bot.Operations) {
+	if err := ops.SetUserTimezone(42, "UTC"); err == nil {
+		t.Fatal("expected an error for a missing user")
+	}
+
+	if _, err := ops.GetUser(42); err == nil {
+		t.Fatal("expected an error for a missing user")
+	}
+}
+
+func testInactiveUsersSkipped(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	shared := storeShow(t, ops, "tvmaze", "1", "Shared", "")
+	own := storeShow(t, ops, "tvmaze", "2", "Own", "")
+	must(t, ops.FollowShow(1, shared))
+	must(t, ops.FollowShow(2, shared))
+	must(t, ops.FollowShow(2, own))
+
+	must(t, ops.EnqueueMessage(&models.OutboxMessage{ChatID: 2, Text: "queued"}))
+	must(t, ops.SetUserActive(2, false))
+
+	user, err := ops.GetUser(2)
+	must(t, err)
+
+	if user.Active || user.DeactivatedAt == nil {
+		t.Fatalf("deactivated user = %+v, want inactive with a timestamp", user)
+	}
+
+	// Profile updates on later messages must not reactivate.
+	storeUser(t, ops, 2)
+
+	shows, err := ops.GetAllFollowedShows()
+	must(t, err)
+
+	if len(shows) != 1 || shows[0] != shared {
+		t.Fatalf("GetAllFollowedShows = %v, want [%s]", shows, shared)
+	}
+
+	episode := storeEpisodeAiring(t, ops, shared, "10", day(2))
+
+	reminders, err := ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if users := reminderUsers(reminders); len(users) != 1 || users[0] != 1 {
+		t.Fatalf("GetUsersToNotify = %v, want [1]", users)
+	}
+
+	queued, err := ops.GetDueOutboxMessages(10)
+	must(t, err)
+
+	if len(queued) != 0 {
+		t.Fatalf("pending messages of a deactivated user = %+v, want none", queued)
+	}
+
+	must(t, ops.SetUserActive(2, true))
+
+	user, err = ops.GetUser(2)
+	must(t, err)
+
+	if !user.Active || user.DeactivatedAt != nil {
+		t.Fatalf("reactivated user = %+v, want active", user)
+	}
+
+	shows, err = ops.GetAllFollowedShows()
+	must(t, err)
+
+	if len(shows) != 2 {
+		t.Fatalf("GetAllFollowedShows after reactivation = %v, want both shows", shows)
+	}
+}
+
+func testFollowUnfollow(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	id := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	must(t, ops.FollowShow(1, id))
+
+	following, err := ops.IsUserFollowingShow(1, id)
+	must(t, err)
+
+	followed, err := ops.IsShowFollowed(1, id)
+	must(t, err)
+
+	if !following || !followed {
+		t.Fatalf("following = %v, followed = %v after FollowShow", following, followed)
+	}
+
+	must(t, ops.UnfollowShow(1, id))
+
+	following, err = ops.IsUserFollowingShow(1, id)
+	must(t, err)
+
+	if following {
+		t.Fatal("still following after UnfollowShow")
+	}
+}
+
+func testFollowShowIdempotent(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	id := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	must(t, ops.FollowShow(1, id))
+	must(t, ops.SetShowMuted(1, id, true))
+	must(t, ops.FollowShow(1, id))
+
+	shows, err := ops.GetUserShows(1)
+	must(t, err)
+
+	if len(shows) != 1 || shows[0].ID != id {
+		t.Fatalf("GetUserShows after following twice = %v, want [%s]", shows, id)
+	}
+
+	muted, err := ops.GetMutedShows(1)
+	must(t, err)
+
+	if len(muted) != 1 {
+		t.Fatalf("following again unmuted the show: GetMutedShows = %v", muted)
+	}
+}
+
+func testFollowRequiresUserAndShow(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	id := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	if err := ops.FollowShow(1, "missing"); err == nil {
+		t.Fatal("following an unknown show succeeded")
+	}
+
+	if err := ops.FollowShow(2, id); err == nil {
+		t.Fatal("an unknown user followed a show")
+	}
+
+	if err := ops.SetShowMuted(1, id, true); err == nil {
+		t.Fatal("muting a show that is not followed succeeded")
+	}
+}
+
+func testGetUserShowsOrderedByName(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	b := storeShow(t, ops, "tvmaze", "2", "Bravo", "")
+	a := storeShow(t, ops, "tvmaze", "1", "Alpha", "")
+	c := storeShow(t, ops, "tvmaze", "3", "Charlie", "")
+
+	must(t, ops.FollowShow(1, b))
+	must(t, ops.FollowShow(1, a))
+	must(t, ops.FollowShow(2, c))
+
+	shows, err := ops.GetUserShows(1)
+	must(t, err)
+
+	if len(shows) != 2 || shows[0].ID != a || shows[1].ID != b {
+		t.Fatalf("GetUserShows = %+v, want [%s %s]", shows, a, b)
+	}
+}
+
+func testGetAllFollowedShowsDistinct(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	id := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	storeShow(t, ops, "tvmaze", "2", "Unfollowed", "")
+
+	must(t, ops.FollowShow(1, id))
+	must(t, ops.FollowShow(2, id))
+
+	ids, err := ops.GetAllFollowedShows()
+	must(t, err)
+
+	if len(ids) != 1 || ids[0] != id {
+		t.Fatalf("GetAllFollowedShows = %v, want [%s]", ids, id)
+	}
+}
+
+func testGetUsersToNotifySkipsNotified(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+	storeUser(t, ops, 3)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(1, showID))
+	must(t, ops.FollowShow(2, showID))
+
+	episode := storeEpisodeAiring(t, ops, showID, "10", day(2))
+
+	must(t, ops.RecordNotification(1, episode.ID, models.KindDefault))
+
+	reminders, err := ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if got := reminderUsers(reminders); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("GetUsersToNotify = %v, want [2]", reminders)
+	}
+
+	if reminders[0].Kinds[0] != models.KindDefault {
+		t.Fatalf("kinds = %v, want [%s]", reminders[0].Kinds, models.KindDefault)
+	}
+}
+
+func testGetUsersToNotifyLeadTimes(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(1, showID))
+	must(t, ops.FollowShow(2, showID))
+
+	must(t, ops.SaveUserSettings(&models.UserSettings{UserID: 2, LeadTimes: "1w,1h"}))
+
+	stamp := time.Now().UTC().Add(30 * time.Minute)
+	episode := storeEpisodeAiring(t, ops, showID, "10", day(0))
+	episode.AirStamp = &stamp
+	_, err := ops.StoreEpisode(episode)
+	must(t, err)
+
+	reminders, err := ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if len(reminders) != 2 || reminders[1].UserID != 2 || len(reminders[1].Kinds) != 2 {
+		t.Fatalf("GetUsersToNotify = %+v, want user 2 with both 1w and 1h due", reminders)
+	}
+
+	for _, kind := range reminders[1].Kinds {
+		must(t, ops.RecordNotification(2, episode.ID, kind))
+	}
+
+	reminders, err = ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if got := reminderUsers(reminders); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("GetUsersToNotify after recording = %v, want [1]", reminders)
+	}
+}
+
+func testGetUsersToNotifySkipsMuted(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(1, showID))
+	must(t, ops.FollowShow(2, showID))
+	must(t, ops.SetShowMuted(1, showID, true))
+
+	muted, err := ops.GetMutedShows(1)
+	must(t, err)
+
+	if len(muted) != 1 || muted[0] != showID {
+		t.Fatalf("GetMutedShows = %v, want [%s]", muted, showID)
+	}
+
+	episode := storeEpisodeAiring(t, ops, showID, "10", day(2))
+
+	reminders, err := ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if got := reminderUsers(reminders); len(got) != 1 || got[0] != 2 {
+		t.Fatalf("GetUsersToNotify = %v, want [2]", reminders)
+	}
+
+	if err = ops.SetShowMuted(1, "missing", true); err == nil {
+		t.Fatal("expected an error muting a show that is not followed")
+	}
+}
+
+func testGetUsersToNotifyQuietHours(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(1, showID))
+
+	// Quiet hours covering the current hour.
+	hour := time.Now().UTC().Hour()
+	must(t, ops.SaveUserSettings(&models.UserSettings{UserID: 1, QuietHours: true, QuietStart: hour, QuietEnd: (hour + 1) % 24}))
+
+	episode := storeEpisodeAiring(t, ops, showID, "10", day(2))
+
+	reminders, err := ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if len(reminders) != 0 {
+		t.Fatalf("GetUsersToNotify during quiet hours = %+v, want none", reminders)
+	}
+}
+
+func testUserSettingsRoundTrip(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+
+	settings, err := ops.GetUserSettings(1)
+	must(t, err)
+
+	if settings.UserID != 1 || settings.LeadTimes != "" || settings.QuietHours {
+		t.Fatalf("default settings = %+v", settings)
+	}
+
+	settings.LeadTimes = "1d"
+	settings.QuietHours, settings.QuietStart, settings.QuietEnd = true, 22, 8
+	must(t, ops.SaveUserSettings(settings))
+
+	settings.QuietHours = false
+	must(t, ops.SaveUserSettings(settings))
+
+	stored, err := ops.GetUserSettings(1)
+	must(t, err)
+
+	if stored.LeadTimes != "1d" || stored.QuietHours || stored.QuietStart != 22 || stored.QuietEnd != 8 {
+		t.Fatalf("stored settings = %+v", stored)
+	}
+}
+
+func testDigestSubscribers(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	settings, err := ops.GetUserSettings(1)
+	must(t, err)
+
+	if settings.Digest != models.DigestOff || settings.DigestHour != 8 || settings.DigestWeekday != time.Monday {
+		t.Fatalf("default digest settings = %+v", settings)
+	}
+
+	settings.Digest, settings.DigestHour, settings.DigestWeekday = models.DigestWeekly, 18, time.Friday
+	must(t, ops.SaveUserSettings(settings))
+	must(t, ops.SaveUserSettings(&models.UserSettings{UserID: 2, LeadTimes: "1h"}))
+
+	subscribers, err := ops.GetDigestSubscribers()
+	must(t, err)
+
+	if len(subscribers) != 1 || subscribers[0].UserID != 1 || subscribers[0].DigestHour != 18 ||
+		subscribers[0].DigestWeekday != time.Friday || subscribers[0].LastDigestAt != nil {
+		t.Fatalf("GetDigestSubscribers = %+v, want user 1 weekly on Friday at 18", subscribers)
+	}
+}
+
+func testDigestEpisodesAndRecording(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+
+	followed := storeShow(t, ops, "tvmaze", "1", "Followed", "")
+	muted := storeShow(t, ops, "tvmaze", "2", "Muted", "")
+	must(t, ops.FollowShow(1, followed))
+	must(t, ops.FollowShow(1, muted))
+	must(t, ops.SetShowMuted(1, muted, true))
+	must(t, ops.SaveUserSettings(&models.UserSettings{UserID: 1, Digest: models.DigestDaily, DigestHour: 8}))
+
+	soon := storeEpisodeAiring(t, ops, followed, "soon", day(1))
+	storeEpisodeAiring(t, ops, followed, "later", day(5))
+	storeEpisodeAiring(t, ops, muted, "muted", day(1))
+
+	from := time.Now().UTC()
+
+	episodes, err := ops.GetEpisodesForDigest(1, from, from.Add(48*time.Hour))
+	must(t, err)
+
+	if len(episodes) != 1 || episodes[0].ID != soon.ID || episodes[0].Show.Name != "Followed" {
+		t.Fatalf("GetEpisodesForDigest = %+v, want [%s] with its show", episodes, soon.ID)
+	}
+
+	// The digest replaces the default reminder.
+	reminders, err := ops.GetUsersToNotify(soon, defaultLead)
+	must(t, err)
+
+	if len(reminders) != 0 {
+		t.Fatalf("GetUsersToNotify for a digest user = %+v, want none", reminders)
+	}
+
+	must(t, ops.RecordDigest(1, []string{soon.ID}, &models.OutboxMessage{ChatID: 1, Text: "digest"}))
+
+	episodes, err = ops.GetEpisodesForDigest(1, from, from.Add(48*time.Hour))
+	must(t, err)
+
+	if len(episodes) != 0 {
+		t.Fatalf("GetEpisodesForDigest after RecordDigest = %+v, want none", episodes)
+	}
+
+	settings, err := ops.GetUserSettings(1)
+	must(t, err)
+
+	if settings.LastDigestAt == nil || settings.Digest != models.DigestDaily {
+		t.Fatalf("settings after RecordDigest = %+v, want LastDigestAt set and digest kept", settings)
+	}
+
+	queued, err := ops.GetDueOutboxMessages(10)
+	must(t, err)
+
+	if len(queued) != 1 || queued[0].Text != "digest" {
+		t.Fatalf("GetDueOutboxMessages = %+v, want the digest", queued)
+	}
+}
+
+func testEnqueueNotificationAtomic(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(1, showID))
+
+	episode := storeEpisodeAiring(t, ops, showID, "10", day(2))
+
+	must(t, ops.EnqueueNotification(1, &models.OutboxMessage{ChatID: 1, Text: "first"}, episode.ID, []string{models.KindDefault}))
+
+	if err := ops.EnqueueNotification(1, &models.OutboxMessage{ChatID: 1, Text: "second"}, episode.ID,
+		[]string{models.KindDefault}); err == nil {
+		t.Fatal("enqueueing an already claimed reminder succeeded")
+	}
+
+	queued, err := ops.GetDueOutboxMessages(10)
+	must(t, err)
+
+	if len(queued) != 1 || queued[0].Text != "first" || queued[0].Status != models.OutboxPending {
+		t.Fatalf("GetDueOutboxMessages = %+v, want only the first message", queued)
+	}
+
+	reminders, err := ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if len(reminders) != 0 {
+		t.Fatalf("GetUsersToNotify after enqueueing = %+v, want none", reminders)
+	}
+}
+
+func testOutboxRetryAndFinalStatus(t *testing.T, ops bot.Operations) {
+	for _, text := range []string{"one", "two", "three"} {
+		must(t, ops.EnqueueMessage(&models.OutboxMessage{ChatID: 1, Text: text}))
+	}
+
+	queued, err := ops.GetDueOutboxMessages(2)
+	must(t, err)
+
+	if len(queued) != 2 || queued[0].Text != "one" || queued[1].Text != "two" {
+		t.Fatalf("GetDueOutboxMessages(2) = %+v, want [one two]", queued)
+	}
+
+	sentAt := time.Now().UTC()
+	queued[0].Status, queued[0].Attempts, queued[0].SentAt = models.OutboxSent, 1, &sentAt
+	must(t, ops.UpdateOutboxMessage(&queued[0]))
+
+	queued[1].Attempts, queued[1].NextAttemptAt, queued[1].LastError = 1, time.Now().UTC().Add(time.Hour), "Too Many Requests"
+	must(t, ops.UpdateOutboxMessage(&queued[1]))
+
+	queued, err = ops.GetDueOutboxMessages(10)
+	must(t, err)
+
+	if len(queued) != 1 || queued[0].Text != "three" {
+		t.Fatalf("GetDueOutboxMessages after updates = %+v, want [three]", queued)
+	}
+}
+
+func testRecordNotificationUnique(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	_, err := ops.StoreEpisode(&models.Episode{ShowID: showID, Name: "Pilot", Provider: "tvmaze", ProviderID: "10"})
+	must(t, err)
+
+	must(t, ops.RecordNotification(1, "tvmaze_10", models.KindDay))
+	must(t, ops.RecordNotification(1, "tvmaze_10", models.KindHour))
+
+	if err = ops.RecordNotification(1, "tvmaze_10", models.KindDay); err == nil {
+		t.Fatal("recording the same notification twice succeeded")
+	}
+}
+
+func testStoreEpisodeIdempotent(t *testing.T, ops bot.Operations) {
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	episode := models.Episode{ShowID: showID, Name: "Pilot", SeasonNumber: 1, EpisodeNumber: 1, Provider: "tvmaze", ProviderID: "10"}
+
+	change, err := ops.StoreEpisode(&episode)
+	must(t, err)
+
+	if episode.ID != "tvmaze_10" || change != nil {
+		t.Fatalf("new episode: id = %q, change = %+v, want tvmaze_10 and no change", episode.ID, change)
+	}
+
+	again := episode
+	again.ID = ""
+
+	change, err = ops.StoreEpisode(&again)
+	must(t, err)
+
+	if again.ID != episode.ID || change != nil {
+		t.Fatalf("unchanged episode: id = %q, change = %+v, want %q and no change", again.ID, change, episode.ID)
+	}
+
+	episodes, err := ops.GetEpisodesForShow(showID)
+	must(t, err)
+
+	if len(episodes) != 1 {
+		t.Fatalf("stored %d episodes, want 1", len(episodes))
+	}
+}
+
+func testStoreEpisodeRecordsChanges(t *testing.T, ops bot.Operations) {
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	episode := models.Episode{ShowID: showID, Name: "TBA", AirDate: day(3), Provider: "tvmaze", ProviderID: "10"}
+	_, err := ops.StoreEpisode(&episode)
+	must(t, err)
+
+	overviewOnly := episode
+	overviewOnly.Overview = "Now with a summary"
+
+	change, err := ops.StoreEpisode(&overviewOnly)
+	must(t, err)
+
+	if change != nil {
+		t.Fatalf("overview update reported change %+v", change)
+	}
+
+	moved := overviewOnly
+	moved.Name = "The Real Title"
+	moved.AirDate = day(10)
+
+	change, err = ops.StoreEpisode(&moved)
+	must(t, err)
+
+	if change == nil || !change.Rescheduled() || !change.Renamed() {
+		t.Fatalf("change = %+v, want rescheduled and renamed", change)
+	}
+
+	if !change.OldAirDate.Equal(day(3)) || !change.NewAirDate.Equal(day(10)) || change.OldName != "TBA" {
+		t.Fatalf("unexpected change %+v", change)
+	}
+
+	episodes, err := ops.GetEpisodesForShow(showID)
+	must(t, err)
+
+	if len(episodes) != 1 || episodes[0].Name != "The Real Title" || !episodes[0].AirDate.Equal(day(10)) ||
+		episodes[0].Overview != "Now with a summary" {
+		t.Fatalf("stored episode not updated: %+v", episodes)
+	}
+
+	history, err := ops.GetEpisodeChanges(episode.ID)
+	must(t, err)
+
+	if len(history) != 1 || history[0].NewName != "The Real Title" {
+		t.Fatalf("GetEpisodeChanges = %+v, want one rename", history)
+	}
+}
+
+func testStoreEpisodeAirStamp(t *testing.T, ops bot.Operations) {
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	episode := models.Episode{ShowID: showID, Name: "Pilot", AirDate: day(3), Provider: "tvmaze", ProviderID: "10"}
+	_, err := ops.StoreEpisode(&episode)
+	must(t, err)
+
+	stamp := day(4).Add(time.Hour)
+	timed := episode
+	timed.AirStamp = &stamp
+
+	change, err := ops.StoreEpisode(&timed)
+	must(t, err)
+
+	if change != nil {
+		t.Fatalf("air time becoming known recorded a change: %+v", change)
+	}
+
+	episodes, err := ops.GetEpisodesForShow(showID)
+	must(t, err)
+
+	if len(episodes) != 1 || !episodes[0].HasAirTime() || !episodes[0].AirTime().Equal(stamp) {
+		t.Fatalf("air stamp not stored: %+v", episodes)
+	}
+
+	later := stamp.Add(time.Hour)
+	moved := timed
+	moved.AirStamp = &later
+
+	change, err = ops.StoreEpisode(&moved)
+	must(t, err)
+
+	if change == nil || !change.Rescheduled() || !change.OldAirDate.Equal(stamp) || !change.NewAirDate.Equal(later) {
+		t.Fatalf("change = %+v, want rescheduled from %v to %v", change, stamp, later)
+	}
+
+	changes, err := ops.GetEpisodeChanges(episode.ID)
+	must(t, err)
+
+	if len(changes) != 1 || !changes[0].OldHasAirTime {
+		t.Fatalf("GetEpisodeChanges = %+v, want one change from an exact air time", changes)
+	}
+
+	// A date-only episode moved to an exact time on another day keeps the old date's precision.
+	dated := storeEpisodeAiring(t, ops, showID, "11", day(3))
+	retimed := *dated
+	retimed.AirDate = day(4)
+	retimed.AirStamp = &later
+
+	change, err = ops.StoreEpisode(&retimed)
+	must(t, err)
+
+	if change == nil || change.OldHasAirTime || !change.OldAirDate.Equal(day(3)) {
+		t.Fatalf("change = %+v, want rescheduled from the date %v", change, day(3))
+	}
+}
+
+func testGetShowFollowers(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 2)
+	storeUser(t, ops, 1)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(2, showID))
+	must(t, ops.FollowShow(1, showID))
+
+	followers, err := ops.GetShowFollowers(showID)
+	must(t, err)
+
+	if len(followers) != 2 || followers[0] != 1 || followers[1] != 2 {
+		t.Fatalf("GetShowFollowers = %v, want [1 2]", followers)
+	}
+}
+
+func testGetShowFollowersSkipsInactiveAndMuted(t *testing.T, ops bot.Operations) {
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	for id := 1; id <= 3; id++ {
+		storeUser(t, ops, int64(id))
+		must(t, ops.FollowShow(id, showID))
+	}
+
+	must(t, ops.SetUserActive(2, false))
+	must(t, ops.SetShowMuted(3, showID, true))
+
+	followers, err := ops.GetShowFollowers(showID)
+	must(t, err)
+
+	if len(followers) != 1 || followers[0] != 1 {
+		t.Fatalf("GetShowFollowers = %v, want [1]", followers)
+	}
+}
+
+func testClearNotifications(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(1, showID))
+	must(t, ops.FollowShow(2, showID))
+
+	episode := storeEpisodeAiring(t, ops, showID, "10", day(2))
+	must(t, ops.MarkEpisodesWatched(2, []string{episode.ID}))
+
+	for _, userID := range []int64{1, 2} {
+		must(t, ops.RecordNotification(userID, episode.ID, models.KindDefault))
+		must(t, ops.RecordNotification(userID, episode.ID, models.KindDigest))
+	}
+
+	must(t, ops.ClearNotifications(episode.ID, []string{models.KindDefault}))
+
+	// Recording again succeeds only for the records that were cleared.
+	if err := ops.RecordNotification(1, episode.ID, models.KindDefault); err != nil {
+		t.Fatalf("default reminder not cleared: %v", err)
+	}
+
+	if err := ops.RecordNotification(1, episode.ID, models.KindDigest); err == nil {
+		t.Fatal("clearing the default reminder also cleared the digest record")
+	}
+
+	if err := ops.RecordNotification(2, episode.ID, models.KindDefault); err == nil {
+		t.Fatal("cleared the reminder of a user who watched the episode")
+	}
+}
+
+func testGetNextEpisode(t *testing.T, ops bot.Operations) {
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	next, err := ops.GetNextEpisode(showID)
+	must(t, err)
+
+	if next != nil {
+		t.Fatalf("GetNextEpisode on a show without episodes = %+v, want nil", next)
+	}
+
+	for i, offset := range []int{-7, 14, 7} {
+		_, err = ops.StoreEpisode(&models.Episode{
+			ShowID:        showID,
+			Name:          "Episode",
+			SeasonNumber:  1,
+			EpisodeNumber: i + 1,
+			AirDate:       day(offset),
+			Provider:      "tvmaze",
+			ProviderID:    string(rune('a' + i)),
+		})
+		must(t, err)
+	}
+
+	next, err = ops.GetNextEpisode(showID)
+	must(t, err)
+
+	if next == nil || next.EpisodeNumber != 3 {
+		t.Fatalf("GetNextEpisode = %+v, want episode 3", next)
+	}
+}
+
+func testGetUpcomingEpisodesForUser(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+
+	followed := storeShow(t, ops, "tvmaze", "1", "Followed", "")
+	other := storeShow(t, ops, "tvmaze", "2", "Other", "")
+	must(t, ops.FollowShow(1, followed))
+
+	episodes := []models.Episode{
+		{ShowID: followed, Name: "Past", EpisodeNumber: 1, AirDate: day(-1), Provider: "tvmaze", ProviderID: "past"},
+		{ShowID: followed, Name: "Later", EpisodeNumber: 3, AirDate: day(10), Provider: "tvmaze", ProviderID: "later"},
+		{ShowID: followed, Name: "Soon", EpisodeNumber: 2, AirDate: day(2), Provider: "tvmaze", ProviderID: "soon"},
+		{ShowID: followed, Name: "Far", EpisodeNumber: 4, AirDate: day(60), Provider: "tvmaze", ProviderID: "far"},
+		{ShowID: other, Name: "Other", EpisodeNumber: 1, AirDate: day(2), Provider: "tvmaze", ProviderID: "other"},
+	}
+
+	for i := range episodes {
+		_, err := ops.StoreEpisode(&episodes[i])
+		must(t, err)
+	}
+
+	upcoming, err := ops.GetUpcomingEpisodesForUser(1)
+	must(t, err)
+
+	if len(upcoming) != 2 || upcoming[0].Name != "Soon" || upcoming[1].Name != "Later" {
+		t.Fatalf("GetUpcomingEpisodesForUser = %+v, want [Soon Later]", upcoming)
+	}
+}
+
+func testUpcomingEpisodesUseAirStamp(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(1, showID))
+
+	// Dated today (UTC midnight, already past) but airing later today.
+	tonight := time.Now().UTC().Add(time.Hour)
+	_, err := ops.StoreEpisode(&models.Episode{
+		ShowID: showID, Name: "Tonight", AirDate: day(0), AirStamp: &tonight, Provider: "tvmaze", ProviderID: "tonight",
+	})
+	must(t, err)
+
+	// Dated in the future but already aired in UTC.
+	aired := time.Now().UTC().Add(-time.Hour)
+	_, err = ops.StoreEpisode(&models.Episode{
+		ShowID: showID, Name: "Aired", AirDate: day(1), AirStamp: &aired, Provider: "tvmaze", ProviderID: "aired",
+	})
+	must(t, err)
+
+	upcoming, err := ops.GetUpcomingEpisodesForUser(1)
+	must(t, err)
+
+	if len(upcoming) != 1 || upcoming[0].Name != "Tonight" {
+		t.Fatalf("GetUpcomingEpisodesForUser = %+v, want [Tonight]", upcoming)
+	}
+
+	next, err := ops.GetNextEpisode(showID)
+	must(t, err)
+
+	if next == nil || next.Name != "Tonight" {
+		t.Fatalf("GetNextEpisode = %+v, want Tonight", next)
+	}
+}
+
+func testGetEpisodesForShowOrdered(t *testing.T, ops bot.Operations) {
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+
+	for _, ep := range []struct{ season, number int }{{2, 1}, {1, 2}, {1, 1}} {
+		_, err := ops.StoreEpisode(&models.Episode{
+			ShowID:        showID,
+			Name:          "Episode",
+			SeasonNumber:  ep.season,
+			EpisodeNumber: ep.number,
+			Provider:      "tvmaze",
+			ProviderID:    string(rune('0'+ep.season)) + string(rune('0'+ep.number)),
+		})
+		must(t, err)
+	}
+
+	episodes, err := ops.GetEpisodesForShow(showID)
+	must(t, err)
+
+	got := make([][2]int, len(episodes))
+	for i, e := range episodes {
+		got[i] = [2]int{e.SeasonNumber, e.EpisodeNumber}
+	}
+
+	want := [][2]int{{1, 1}, {1, 2}, {2, 1}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("GetEpisodesForShow order = %v, want %v", got, want)
+	}
+}
+
+func testGetEpisodeLoadsShow(t *testing.T, ops bot.Operations) {
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	stored := storeEpisodeAiring(t, ops, showID, "10", day(1))
+
+	episode, err := ops.GetEpisode(stored.ID)
+	must(t, err)
+
+	if episode.Name != "Pilot" || episode.Show.Name != "Show" {
+		t.Fatalf("GetEpisode = %+v, want Pilot of Show", episode)
+	}
+
+	if _, err = ops.GetEpisode("missing"); err == nil {
+		t.Fatal("expected an error for a missing episode")
+	}
+}
+
+func testWatchedEpisodes(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	otherID := storeShow(t, ops, "tvmaze", "2", "Other", "")
+	first := storeEpisodeAiring(t, ops, showID, "10", day(-14))
+	second := storeEpisodeAiring(t, ops, showID, "11", day(-7))
+	other := storeEpisodeAiring(t, ops, otherID, "20", day(-7))
+
+	must(t, ops.MarkEpisodesWatched(1, []string{second.ID, first.ID, other.ID}))
+	must(t, ops.MarkEpisodesWatched(1, []string{first.ID}))
+	must(t, ops.MarkEpisodesWatched(1, nil))
+
+	watched, err := ops.GetWatchedEpisodes(1, showID)
+	must(t, err)
+
+	if len(watched) != 2 || watched[0] != first.ID || watched[1] != second.ID {
+		t.Fatalf("GetWatchedEpisodes = %v, want [%s %s]", watched, first.ID, second.ID)
+	}
+
+	must(t, ops.UnmarkEpisodeWatched(1, first.ID))
+
+	watched, err = ops.GetWatchedEpisodes(1, showID)
+	must(t, err)
+
+	if len(watched) != 1 || watched[0] != second.ID {
+		t.Fatalf("GetWatchedEpisodes after unmarking = %v, want [%s]", watched, second.ID)
+	}
+
+	if err = ops.MarkEpisodesWatched(1, []string{"missing"}); err == nil {
+		t.Fatal("marking an unknown episode watched succeeded")
+	}
+
+	if err = ops.MarkEpisodesWatched(2, []string{first.ID}); err == nil {
+		t.Fatal("an unknown user marked an episode watched")
+	}
+}
+
+func testWatchedEpisodesSuppressReminders(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(1, showID))
+	must(t, ops.FollowShow(2, showID))
+	must(t, ops.SaveUserSettings(&models.UserSettings{UserID: 2, Digest: models.DigestDaily}))
+
+	// Screeners and early releases can be watched before the air date.
+	episode := storeEpisodeAiring(t, ops, showID, "10", day(1))
+	must(t, ops.MarkEpisodesWatched(1, []string{episode.ID}))
+	must(t, ops.MarkEpisodesWatched(2, []string{episode.ID}))
+
+	reminders, err := ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if len(reminders) != 0 {
+		t.Fatalf("GetUsersToNotify for a watched episode = %+v, want none", reminders)
+	}
+
+	from := time.Now().UTC()
+
+	episodes, err := ops.GetEpisodesForDigest(2, from, from.Add(48*time.Hour))
+	must(t, err)
+
+	if len(episodes) != 0 {
+		t.Fatalf("GetEpisodesForDigest with a watched episode = %+v, want none", episodes)
+	}
+
+	must(t, ops.UnmarkEpisodeWatched(1, episode.ID))
+
+	reminders, err = ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if got := reminderUsers(reminders); len(got) != 1 || got[0] != 1 {
+		t.Fatalf("GetUsersToNotify after unmarking = %v, want [1]", reminders)
+	}
+}
+
+func testCalendarToken(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+	storeUser(t, ops, 2)
+
+	if _, err := ops.GetUserByCalendarToken("first"); err == nil {
+		t.Fatal("expected an error for an unknown calendar token")
+	}
+
+	must(t, ops.SetCalendarToken(1, "first"))
+
+	user, err := ops.GetUserByCalendarToken("first")
+	must(t, err)
+
+	if user.ID != 1 {
+		t.Fatalf("GetUserByCalendarToken = user %d, want 1", user.ID)
+	}
+
+	must(t, ops.SetCalendarToken(1, "second"))
+
+	if _, err = ops.GetUserByCalendarToken("first"); err == nil {
+		t.Fatal("the rotated calendar token still resolves")
+	}
+
+	if err = ops.SetCalendarToken(2, "second"); err == nil {
+		t.Fatal("two users share a calendar token")
+	}
+
+	// Re-storing the Telegram profile keeps the token.
+	storeUser(t, ops, 1)
+
+	user, err = ops.GetUser(1)
+	must(t, err)
+
+	if user.CalendarToken == nil || *user.CalendarToken != "second" {
+		t.Fatalf("CalendarToken after StoreUser = %v, want second", user.CalendarToken)
+	}
+
+	if err = ops.SetCalendarToken(3, "third"); err == nil {
+		t.Fatal("expected an error for a missing user")
+	}
+}
+
+func testGetCalendarEpisodes(t *testing.T, ops bot.Operations) {
+	storeUser(t, ops, 1)
+
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	muted := storeShow(t, ops, "tvmaze", "2", "Muted", "")
+	other := storeShow(t, ops, "tvmaze", "3", "Other", "")
+	must(t, ops.FollowShow(1, showID))
+	must(t, ops.FollowShow(1, muted))
+	must(t, ops.SetShowMuted(1, muted, true))
+
+	storeEpisodeAiring(t, ops, showID, "10", day(-10))
+	later := storeEpisodeAiring(t, ops, showID, "11", day(5))
+	soon := storeEpisodeAiring(t, ops, muted, "20", day(1))
+	storeEpisodeAiring(t, ops, showID, "12", time.Time{})
+	storeEpisodeAiring(t, ops, other, "30", day(2))
+
+	episodes, err := ops.GetCalendarEpisodes(1, day(-1))
+	must(t, err)
+
+	if len(episodes) != 2 || episodes[0].ID != soon.ID || episodes[1].ID != later.ID {
+		t.Fatalf("GetCalendarEpisodes = %+v, want [%s %s]", episodes, soon.ID, later.ID)
+	}
+
+	if episodes[0].Show.Name != "Muted" || episodes[1].Show.Name != "Show" {
+		t.Fatalf("GetCalendarEpisodes shows = %q, %q, want Muted, Show", episodes[0].Show.Name, episodes[1].Show.Name)
+	}
+}
+
+func testGetShowByIMDbID(t *testing.T, ops bot.Operations) {
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "tt0000001")
+	storeShow(t, ops, "tvmaze", "2", "No IMDb", "")
+
+	show, err := ops.GetShowByIMDbID("tt0000001")
+	must(t, err)
+
+	if show.ID != showID {
+		t.Fatalf("GetShowByIMDbID = %s, want %s", show.ID, showID)
+	}
+
+	if _, err = ops.GetShowByIMDbID("tt0000002"); err == nil {
+		t.Fatal("expected an error for an unknown IMDb ID")
+	}
+
+	if _, err = ops.GetShowByIMDbID(""); err == nil {
+		t.Fatal("an empty IMDb ID matched a show without one")
+	}
+}
+
+func testNotifyChat(t *testing.T, ops bot.Operations) {
+	const (
+		group   = -1001
+		other   = -1002
+		channel = -2001
+	)
+
+	storeUser(t, ops, group)
+	storeUser(t, ops, other)
+
+	channelID := int64(channel)
+	must(t, ops.SetNotifyChat(group, &channelID))
+	must(t, ops.SetNotifyChat(other, &channelID))
+
+	// Re-storing the chat keeps the link.
+	storeUser(t, ops, group)
+
+	user, err := ops.GetUser(group)
+	must(t, err)
+
+	if user.NotifyChatID == nil || *user.NotifyChatID != channel {
+		t.Fatalf("NotifyChatID = %v, want %d", user.NotifyChatID, channel)
+	}
+
+	must(t, ops.SetNotifyChat(other, nil))
+
+	cleared, err := ops.ClearNotifyChat(channel)
+	must(t, err)
+
+	if cleared != 1 {
+		t.Fatalf("ClearNotifyChat cleared %d subscribers, want 1", cleared)
+	}
+
+	user, err = ops.GetUser(group)
+	must(t, err)
+
+	if user.NotifyChatID != nil {
+		t.Fatalf("NotifyChatID after ClearNotifyChat = %d, want nil", *user.NotifyChatID)
+	}
+
+	if err = ops.SetNotifyChat(3, &channelID); err == nil {
+		t.Fatal("expected an error for a missing user")
+	}
+
+	// Reminders are claimed for the group while the message goes to the channel.
+	showID := storeShow(t, ops, "tvmaze", "1", "Show", "")
+	must(t, ops.FollowShow(group, showID))
+
+	episode := storeEpisodeAiring(t, ops, showID, "10", day(2))
+	must(t, ops.EnqueueNotification(group, &models.OutboxMessage{ChatID: channel, Text: "airing"}, episode.ID,
+		[]string{models.KindDefault}))
+
+	reminders, err := ops.GetUsersToNotify(episode, defaultLead)
+	must(t, err)
+
+	if len(reminders) != 0 {
+		t.Fatalf("GetUsersToNotify after enqueueing = %+v, want none", reminders)
+	}
+}
@@ -9,15 +9,29 @@ import (
 	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 
 	"github.com/dkhalizov/shows/internal/config"
 	"github.com/dkhalizov/shows/internal/models"
 )
 
+const (
+	dialectPostgres = "postgres"
+	dialectSQLite   = "sqlite"
+
+	// airTimeColumn orders and filters episodes by exact air time where known, see models.Episode.AirTime.
+	airTimeColumn = "COALESCE(air_stamp, air_date)"
+
+	// postgresSchema holds all bot tables on Postgres; SQLite has no schemas.
+	postgresSchema = "shows_bot"
+)
+
 type Manager struct {
-	db     *gorm.DB
-	config config.Database
+	db      *gorm.DB
+	config  config.Database
+	dialect string
 }
 
 func NewManager(config config.Database) (*Manager, error) {
@@ -30,19 +44,23 @@ func NewManager(config config.Database) (*Manager, error) {
 		logLevel = logger.Info
 	}
 
+	dialect := dialectOf(config.DatabaseURL)
+
 	gormConfig := &gorm.Config{
-		Logger: logger.Default.LogMode(logLevel),
+		Logger:         logger.Default.LogMode(logLevel),
+		NamingStrategy: namingStrategy(dialect),
+		NowFunc:        func() time.Time { return time.Now().UTC() },
 	}
 
 	switch {
-	case strings.HasPrefix(config.DatabaseURL, "postgres"):
+	case dialect == dialectPostgres:
 		db, err = gorm.Open(postgres.Open(config.DatabaseURL), gormConfig)
 	case strings.HasPrefix(config.DatabaseURL, "sqlite"):
-		db, err = gorm.Open(sqlite.Open(strings.TrimPrefix(config.DatabaseURL, "sqlite://")), gormConfig)
+		db, err = gorm.Open(sqlite.Open(sqliteDSN(strings.TrimPrefix(config.DatabaseURL, "sqlite://"))), gormConfig)
 	case strings.HasPrefix(config.DatabaseURL, "file:"):
-		db, err = gorm.Open(sqlite.Open(strings.TrimPrefix(config.DatabaseURL, "file://")), gormConfig)
+		db, err = gorm.Open(sqlite.Open(sqliteDSN(strings.TrimPrefix(config.DatabaseURL, "file://"))), gormConfig)
 	default:
-		db, err = gorm.Open(sqlite.Open(config.DatabaseURL), gormConfig)
+		db, err = gorm.Open(sqlite.Open(sqliteDSN(config.DatabaseURL)), gormConfig)
 	}
 
 	if err != nil {
@@ -54,7 +72,12 @@ func NewManager(config config.Database) (*Manager, error) {
 		return nil, fmt.Errorf("failed to get database connection: %w", err)
 	}
 
-	if config.MaxConnections > 0 {
+	switch {
+	case dialect == dialectSQLite:
+		// SQLite allows a single writer, and every connection to :memory: is a separate
+		// database, so all access goes through one long-lived connection.
+		sqlDB.SetMaxOpenConns(1)
+	case config.MaxConnections > 0:
 		sqlDB.SetMaxOpenConns(int(config.MaxConnections))
 	}
 
@@ -62,40 +85,154 @@ func NewManager(config config.Database) (*Manager, error) {
 		sqlDB.SetMaxIdleConns(int(config.MaxIdleConnections))
 	}
 
-	if config.ConnectionLifetime > 0 {
+	if config.ConnectionLifetime > 0 && dialect != dialectSQLite {
 		sqlDB.SetConnMaxLifetime(config.ConnectionLifetime)
 	}
 
-	return &Manager{db: db, config: config}, nil
+	return &Manager{db: db, config: config, dialect: dialect}, nil
 }
 
-func (m *Manager) Init() error {
-	if strings.HasPrefix(m.config.DatabaseURL, "postgres") {
-		m.db.Exec("CREATE SCHEMA IF NOT EXISTS shows_bot")
-		// set schema
-		m.db.Exec("SET search_path TO shows_bot")
+func dialectOf(databaseURL string) string {
+	if strings.HasPrefix(databaseURL, "postgres") {
+		return dialectPostgres
+	}
+
+	return dialectSQLite
+}
+
+// namingStrategy places every table in the shows_bot schema on Postgres
+// and uses plain table names on SQLite, which has no schemas.
+func namingStrategy(dialect string) schema.NamingStrategy {
+	if dialect == dialectPostgres {
+		return schema.NamingStrategy{TablePrefix: postgresSchema + "."}
+	}
+
+	return schema.NamingStrategy{}
+}
+
+// sqliteDSN enables foreign key enforcement, which SQLite leaves off by default.
+func sqliteDSN(dsn string) string {
+	if strings.Contains(dsn, "?") {
+		return dsn + "&_foreign_keys=1"
 	}
 
+	return dsn + "?_foreign_keys=1"
+}
+
+// now returns the current time in UTC; timestamps are stored without a zone,
+// so every value written or compared has to use the same one.
+func (m *Manager) now() time.Time {
+	return m.db.NowFunc()
+}
+
+// table returns the dialect-qualified name of a bot table for raw SQL fragments.
+func (m *Manager) table(name string) string {
+	if m.dialect == dialectPostgres {
+		return postgresSchema + "." + name
+	}
+
+	return name
+}
+
+// Init applies pending schema migrations, refusing to start on a dirty schema.
+func (m *Manager) Init() error {
 	slog.Debug("Running database migrations...")
 
-	err := m.db.AutoMigrate(
-		&models.User{},
-		&models.Show{},
-		&models.Episode{},
-		&models.Notification{},
-		&models.UserShow{},
-	)
+	applied, err := m.MigrateUp()
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
 
-	slog.Debug("Database migrations completed successfully")
+	slog.Debug("Database migrations completed successfully", "applied", applied)
 
 	return nil
 }
 
+func (m *Manager) Close() error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+
+	return sqlDB.Close()
+}
+
+// StoreUser inserts the user or refreshes their Telegram profile, keeping bot settings intact.
 func (m *Manager) StoreUser(user models.User) error {
-	return m.db.Save(&user).Error
+	return m.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"username", "first_name", "last_name"}),
+	}).Create(&user).Error
+}
+
+func (m *Manager) GetUser(userID int64) (*models.User, error) {
+	var user models.User
+
+	result := m.db.First(&user, "id = ?", userID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+func (m *Manager) SetUserTimezone(userID int64, timezone string) error {
+	result := m.db.Model(&models.User{}).Where("id = ?", userID).Update("timezone", timezone)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// SetCalendarToken replaces the secret of the user's calendar feed, invalidating the previous link.
+func (m *Manager) SetCalendarToken(userID int64, token string) error {
+	result := m.db.Model(&models.User{}).Where("id = ?", userID).Update("calendar_token", token)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *Manager) GetUserByCalendarToken(token string) (*models.User, error) {
+	var user models.User
+
+	result := m.db.First(&user, "calendar_token = ?", token)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &user, nil
+}
+
+// SetNotifyChat routes the user's notifications to chatID, or back to the user's own chat when chatID is nil.
+func (m *Manager) SetNotifyChat(userID int64, chatID *int64) error {
+	result := m.db.Model(&models.User{}).Where("id = ?", userID).Update("notify_chat_id", chatID)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ClearNotifyChat unlinks chatID from every subscriber that posts to it and returns how many did.
+func (m *Manager) ClearNotifyChat(chatID int64) (int64, error) {
+	result := m.db.Model(&models.User{}).Where("notify_chat_id = ?", chatID).Update("notify_chat_id", nil)
+
+	return result.RowsAffected, result.Error
 }
 
 func (m *Manager) StoreShow(show *models.Show) (string, error) {
@@ -144,8 +281,71 @@ func (m *Manager) GetShow(id string) (*models.Show, error) {
 	return &show, nil
 }
 
+func (m *Manager) GetShowByIMDbID(imdbID string) (*models.Show, error) {
+	var show models.Show
+
+	result := m.db.First(&show, "imdb_id = ? AND imdb_id != ''", imdbID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &show, nil
+}
+
+// UpdateShowDetails overwrites the stored show with freshly fetched provider details and
+// returns the status it had before. Empty overview, poster and IMDb values keep the stored ones.
+func (m *Manager) UpdateShowDetails(details *models.Show) (string, error) {
+	var oldStatus string
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		var show models.Show
+		if err := tx.First(&show, "id = ?", details.ID).Error; err != nil {
+			return err
+		}
+
+		oldStatus = show.Status
+
+		updates := map[string]any{
+			"refreshed_at": m.now(),
+		}
+
+		// A provider that omits the status says nothing about it changing.
+		if details.Status != "" {
+			updates["status"] = details.Status
+		}
+
+		if details.Name != "" {
+			updates["name"] = details.Name
+		}
+
+		if !details.FirstAirDate.IsZero() {
+			updates["first_air_date"] = details.FirstAirDate
+		}
+
+		if details.Overview != "" {
+			updates["overview"] = details.Overview
+		}
+
+		if details.PosterURL != "" {
+			updates["poster_url"] = details.PosterURL
+		}
+
+		if details.IMDbID != "" && details.IMDbID != "0" {
+			updates["imdb_id"] = details.IMDbID
+		}
+
+		return tx.Model(&show).Updates(updates).Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("error updating show %s: %w", details.ID, err)
+	}
+
+	return oldStatus, nil
+}
+
+// FollowShow subscribes the user to the show; following a show twice keeps the first subscription.
 func (m *Manager) FollowShow(userID int, showID string) error {
-	return m.db.Create(&models.UserShow{
+	return m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.UserShow{
 		UserID: int64(userID),
 		ShowID: showID,
 	}).Error
@@ -163,41 +363,230 @@ func (m *Manager) IsUserFollowingShow(userID int, showID string) (bool, error) {
 }
 
 func (m *Manager) GetUserShows(userID int) ([]models.Show, error) {
+	userShows, showsTable := m.table("user_shows"), m.table("shows")
+
 	var shows []models.Show
-	err := m.db.Joins("JOIN shows_bot.user_shows ON shows_bot.user_shows.show_id = shows_bot.shows.id").
-		Where("shows_bot.user_shows.user_id = ?", userID).
-		Order("shows_bot.shows.name").
+	err := m.db.Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.show_id = %[2]s.id", userShows, showsTable)).
+		Where(userShows+".user_id = ?", userID).
+		Order(showsTable + ".name").
 		Find(&shows).Error
 
 	return shows, err
 }
 
+// GetAllFollowedShows returns the shows followed by at least one active user.
 func (m *Manager) GetAllFollowedShows() ([]string, error) {
+	userShows, users := m.table("user_shows"), m.table("users")
+
 	var showIDs []string
-	err := m.db.Model(&models.UserShow{}).Distinct().Pluck("show_id", &showIDs).Error
+	err := m.db.Model(&models.UserShow{}).
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.id = %[2]s.user_id", users, userShows)).
+		Where(users+".active").
+		Distinct().
+		Pluck(userShows+".show_id", &showIDs).Error
 
 	return showIDs, err
 }
 
-func (m *Manager) GetUsersToNotify(episodeID, showID string) ([]int64, error) {
-	var userIDs []int64
-	err := m.db.Model(&models.User{}).
-		Joins("JOIN shows_bot.user_shows ON shows_bot.user_shows.user_id = shows_bot.users.id").
-		Joins("LEFT JOIN shows_bot.notifications ON shows_bot.notifications.user_id = shows_bot.users.id AND shows_bot.notifications.episode_id = ?", episodeID).
-		Where("shows_bot.user_shows.show_id = ? AND shows_bot.notifications.id IS NULL", showID).
-		Pluck("shows_bot.users.id", &userIDs).Error
+// SetUserActive marks a user as reachable or not. Deactivating also stops their pending
+// outbox messages, which could only fail the same way.
+func (m *Manager) SetUserActive(userID int64, active bool) error {
+	var deactivatedAt *time.Time
 
-	return userIDs, err
+	if !active {
+		now := m.now()
+		deactivatedAt = &now
+	}
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.User{}).Where("id = ? AND active = ?", userID, !active).
+			Updates(map[string]any{"active": active, "deactivated_at": deactivatedAt}).Error
+		if err != nil || active {
+			return err
+		}
+
+		return tx.Model(&models.OutboxMessage{}).
+			Where("chat_id = ? AND status = ?", userID, models.OutboxPending).
+			Updates(map[string]any{"status": models.OutboxBlocked, "last_error": "user deactivated"}).Error
+	})
 }
 
-func (m *Manager) RecordNotification(userID int64, episodeID string) error {
+// GetUsersToNotify returns the followers of the episode's show who have a reminder due now,
+// skipping muted shows, watched episodes and reminder kinds already sent. Users without settings get a single
+// reminder within defaultLead of the air time.
+func (m *Manager) GetUsersToNotify(episode *models.Episode, defaultLead time.Duration) ([]models.Reminder, error) {
+	users, userShows, userSettings := m.table("users"), m.table("user_shows"), m.table("user_settings")
+	watchedEpisodes := m.table("watched_episodes")
+
+	var followers []struct {
+		models.UserSettings
+		Timezone string
+	}
+
+	err := m.db.Table(userShows).
+		Select(fmt.Sprintf("%[1]s.user_id, %[2]s.timezone, "+
+			"%[3]s.lead_times, %[3]s.quiet_hours, %[3]s.quiet_start, %[3]s.quiet_end, %[3]s.digest",
+			userShows, users, userSettings)).
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.id = %[2]s.user_id", users, userShows)).
+		Joins(fmt.Sprintf("LEFT JOIN %[1]s ON %[1]s.user_id = %[2]s.user_id", userSettings, userShows)).
+		Where(userShows+".show_id = ? AND NOT "+userShows+".muted AND "+users+".active", episode.ShowID).
+		Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.user_id = %[2]s.user_id AND %[1]s.episode_id = ?)",
+			watchedEpisodes, userShows), episode.ID).
+		Order(userShows + ".user_id").
+		Scan(&followers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var sentRows []models.Notification
+	if err = m.db.Select("user_id", "kind").Where("episode_id = ?", episode.ID).Find(&sentRows).Error; err != nil {
+		return nil, err
+	}
+
+	sent := make(map[int64]map[string]bool)
+	for _, row := range sentRows {
+		if sent[row.UserID] == nil {
+			sent[row.UserID] = make(map[string]bool)
+		}
+
+		sent[row.UserID][row.Kind] = true
+	}
+
+	now := m.now()
+
+	var reminders []models.Reminder
+
+	for _, follower := range followers {
+		loc := models.User{Timezone: follower.Timezone}.Location()
+
+		kinds := follower.DueReminders(episode.AirTime(), now, loc, defaultLead, sent[follower.UserID])
+		if len(kinds) > 0 {
+			reminders = append(reminders, models.Reminder{UserID: follower.UserID, Kinds: kinds})
+		}
+	}
+
+	return reminders, nil
+}
+
+func (m *Manager) RecordNotification(userID int64, episodeID, kind string) error {
 	return m.db.Create(&models.Notification{
 		UserID:     userID,
 		EpisodeID:  episodeID,
-		NotifiedAt: time.Now(),
+		Kind:       kind,
+		NotifiedAt: m.now(),
 	}).Error
 }
 
+// GetUserSettings returns the user's notification preferences, or defaults if they never changed them.
+func (m *Manager) GetUserSettings(userID int64) (*models.UserSettings, error) {
+	settings := models.DefaultUserSettings(userID)
+	if err := m.db.Where("user_id = ?", userID).Limit(1).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (m *Manager) SaveUserSettings(settings *models.UserSettings) error {
+	settings.UpdatedAt = m.now()
+
+	return m.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"lead_times", "quiet_hours", "quiet_start", "quiet_end",
+			"digest", "digest_hour", "digest_weekday", "updated_at",
+		}),
+	}).Create(settings).Error
+}
+
+func (m *Manager) GetDigestSubscribers() ([]models.UserSettings, error) {
+	userSettings, users := m.table("user_settings"), m.table("users")
+
+	var settings []models.UserSettings
+	err := m.db.Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.id = %[2]s.user_id", users, userSettings)).
+		Where(userSettings+".digest <> ? AND "+users+".active", models.DigestOff).
+		Order(userSettings + ".user_id").
+		Find(&settings).Error
+
+	return settings, err
+}
+
+// GetEpisodesForDigest returns episodes of the user's unmuted shows airing in [from, to)
+// that no earlier digest listed and the user has not watched, ordered by air time, with their show loaded.
+func (m *Manager) GetEpisodesForDigest(userID int64, from, to time.Time) ([]models.Episode, error) {
+	userShows, episodesTable, notifications := m.table("user_shows"), m.table("episodes"), m.table("notifications")
+	watchedEpisodes := m.table("watched_episodes")
+	airTime := fmt.Sprintf("COALESCE(%[1]s.air_stamp, %[1]s.air_date)", episodesTable)
+
+	var episodes []models.Episode
+	err := m.db.Preload("Show").
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.show_id = %[2]s.show_id", userShows, episodesTable)).
+		Joins(fmt.Sprintf("LEFT JOIN %[1]s ON %[1]s.episode_id = %[2]s.id AND %[1]s.user_id = %[3]s.user_id AND %[1]s.kind = ?",
+			notifications, episodesTable, userShows), models.KindDigest).
+		Where(fmt.Sprintf("%[1]s.user_id = ? AND NOT %[1]s.muted AND %[2]s >= ? AND %[2]s < ? AND %[3]s.id IS NULL",
+			userShows, airTime, notifications), userID, from, to).
+		Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.user_id = %[2]s.user_id AND %[1]s.episode_id = %[3]s.id)",
+			watchedEpisodes, userShows, episodesTable)).
+		Order(airTime).
+		Find(&episodes).Error
+
+	return episodes, err
+}
+
+// RecordDigest marks the episodes as sent in a digest, enqueues the digest message
+// (nil when there was nothing to send) and stamps the user's last digest time in one transaction.
+func (m *Manager) RecordDigest(userID int64, episodeIDs []string, msg *models.OutboxMessage) error {
+	now := m.now()
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, episodeID := range episodeIDs {
+			err := tx.Create(&models.Notification{
+				UserID:     userID,
+				EpisodeID:  episodeID,
+				Kind:       models.KindDigest,
+				NotifiedAt: now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if msg != nil {
+			if err := m.enqueue(tx, msg); err != nil {
+				return err
+			}
+		}
+
+		settings := models.DefaultUserSettings(userID)
+		settings.LastDigestAt = &now
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"last_digest_at"}),
+		}).Create(&settings).Error
+	})
+}
+
+func (m *Manager) SetShowMuted(userID int64, showID string, muted bool) error {
+	result := m.db.Model(&models.UserShow{}).Where("user_id = ? AND show_id = ?", userID, showID).Update("muted", muted)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *Manager) GetMutedShows(userID int64) ([]string, error) {
+	var showIDs []string
+	err := m.db.Model(&models.UserShow{}).Where("user_id = ? AND muted", userID).Order("show_id").Pluck("show_id", &showIDs).Error
+
+	return showIDs, err
+}
+
 func (m *Manager) IsShowFollowed(userID int64, showID string) (bool, error) {
 	var count int64
 	err := m.db.Model(&models.UserShow{}).Where("user_id = ? AND show_id = ?", userID, showID).Count(&count).Error
@@ -205,7 +594,9 @@ func (m *Manager) IsShowFollowed(userID int64, showID string) (bool, error) {
 	return count > 0, err
 }
 
-func (m *Manager) StoreEpisode(episode *models.Episode) (string, error) {
+// StoreEpisode inserts a new episode or updates the stored copy with the provider's
+// latest data. Air date and name changes are recorded and returned; otherwise the change is nil.
+func (m *Manager) StoreEpisode(episode *models.Episode) (*models.EpisodeChange, error) {
 	if episode.ID == "" {
 		episode.ID = fmt.Sprintf("%s_%s", episode.Provider, episode.ProviderID)
 	}
@@ -214,24 +605,169 @@ func (m *Manager) StoreEpisode(episode *models.Episode) (string, error) {
 
 	result := m.db.Find(&existingEpisode, "id = ?", episode.ID)
 	if result.Error != nil {
-		return "", fmt.Errorf("error finding episode: %w", result.Error)
+		return nil, fmt.Errorf("error finding episode: %w", result.Error)
 	}
 
-	if existingEpisode.ID != "" {
-		return existingEpisode.ID, nil
+	if existingEpisode.ID == "" {
+		return nil, m.db.Omit(clause.Associations).Create(episode).Error
 	}
 
-	if err := m.db.Create(episode).Error; err != nil {
-		return "", err
+	// An air time becoming known for the same date is new detail, not a reschedule.
+	rescheduled := !existingEpisode.AirDate.Equal(episode.AirDate) ||
+		existingEpisode.HasAirTime() && episode.HasAirTime() && !existingEpisode.AirStamp.Equal(*episode.AirStamp)
+
+	if existingEpisode.Name == episode.Name &&
+		!rescheduled &&
+		existingEpisode.HasAirTime() == episode.HasAirTime() &&
+		existingEpisode.Overview == episode.Overview &&
+		existingEpisode.SeasonNumber == episode.SeasonNumber &&
+		existingEpisode.EpisodeNumber == episode.EpisodeNumber {
+		return nil, nil
+	}
+
+	var change *models.EpisodeChange
+	if existingEpisode.Name != episode.Name || rescheduled {
+		change = &models.EpisodeChange{
+			EpisodeID:  episode.ID,
+			OldAirDate: existingEpisode.AirTime(),
+			NewAirDate: existingEpisode.AirTime(),
+			OldName:    existingEpisode.Name,
+			NewName:    episode.Name,
+			ChangedAt:  m.now(),
+
+			OldHasAirTime: existingEpisode.HasAirTime(),
+		}
+
+		if rescheduled {
+			change.NewAirDate = episode.AirTime()
+		}
+	}
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&existingEpisode).Updates(map[string]any{
+			"name":           episode.Name,
+			"air_date":       episode.AirDate,
+			"air_stamp":      episode.AirStamp,
+			"overview":       episode.Overview,
+			"season_number":  episode.SeasonNumber,
+			"episode_number": episode.EpisodeNumber,
+			"updated_at":     m.now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if change != nil {
+			return tx.Create(change).Error
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error updating episode %s: %w", episode.ID, err)
 	}
 
-	return episode.ID, nil
+	return change, nil
+}
+
+func (m *Manager) GetEpisodeChanges(episodeID string) ([]models.EpisodeChange, error) {
+	var changes []models.EpisodeChange
+	err := m.db.Where("episode_id = ?", episodeID).Order("changed_at, id").Find(&changes).Error
+
+	return changes, err
+}
+
+// GetShowFollowers returns the active users who follow showID without muting it.
+func (m *Manager) GetShowFollowers(showID string) ([]int64, error) {
+	userShows, users := m.table("user_shows"), m.table("users")
+
+	var userIDs []int64
+	err := m.db.Model(&models.UserShow{}).
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.id = %[2]s.user_id", users, userShows)).
+		Where(userShows+".show_id = ? AND NOT "+userShows+".muted AND "+users+".active", showID).
+		Order(userShows+".user_id").
+		Pluck(userShows+".user_id", &userIDs).Error
+
+	return userIDs, err
+}
+
+// ClearNotifications forgets sent reminders of the given kinds for an episode so they go out again
+// for a new air date. Users who watched the episode keep theirs.
+func (m *Manager) ClearNotifications(episodeID string, kinds []string) error {
+	if len(kinds) == 0 {
+		return nil
+	}
+
+	notifications, watchedEpisodes := m.table("notifications"), m.table("watched_episodes")
+
+	return m.db.
+		Where("episode_id = ? AND kind IN ?", episodeID, kinds).
+		Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.user_id = %[2]s.user_id AND %[1]s.episode_id = %[2]s.episode_id)",
+			watchedEpisodes, notifications)).
+		Delete(&models.Notification{}).Error
+}
+
+func (m *Manager) enqueue(tx *gorm.DB, msg *models.OutboxMessage) error {
+	msg.Status = models.OutboxPending
+	msg.NextAttemptAt = m.now()
+
+	return tx.Create(msg).Error
+}
+
+// EnqueueMessage queues a message for the outbox dispatcher.
+func (m *Manager) EnqueueMessage(msg *models.OutboxMessage) error {
+	return m.enqueue(m.db, msg)
+}
+
+// EnqueueNotification records the user's episode reminder kinds and queues its message in one
+// transaction, so a reminder is either both claimed and queued or neither. The message may go to
+// another chat than the user's, e.g. a linked channel.
+func (m *Manager) EnqueueNotification(userID int64, msg *models.OutboxMessage, episodeID string, kinds []string) error {
+	now := m.now()
+
+	return m.db.Transaction(func(tx *gorm.DB) error {
+		for _, kind := range kinds {
+			err := tx.Create(&models.Notification{
+				UserID:     userID,
+				EpisodeID:  episodeID,
+				Kind:       kind,
+				NotifiedAt: now,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return m.enqueue(tx, msg)
+	})
+}
+
+// GetDueOutboxMessages returns up to limit pending messages whose next attempt is due, oldest first.
+func (m *Manager) GetDueOutboxMessages(limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := m.db.Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, m.now()).
+		Order("id").
+		Limit(limit).
+		Find(&messages).Error
+
+	return messages, err
+}
+
+// UpdateOutboxMessage stores the outcome of a delivery attempt.
+func (m *Manager) UpdateOutboxMessage(msg *models.OutboxMessage) error {
+	return m.db.Model(msg).Updates(map[string]any{
+		"status":          msg.Status,
+		"attempts":        msg.Attempts,
+		"next_attempt_at": msg.NextAttemptAt,
+		"last_error":      msg.LastError,
+		"sent_at":         msg.SentAt,
+	}).Error
 }
 
 func (m *Manager) GetNextEpisode(showID string) (*models.Episode, error) {
 	var episode models.Episode
-	result := m.db.Where("show_id = ? AND air_date > ?", showID, time.Now()).
-		Order("air_date").
+	result := m.db.Where("show_id = ? AND "+airTimeColumn+" > ?", showID, m.now()).
+		Order(airTimeColumn).
 		Find(&episode)
 
 	if result.Error == gorm.ErrRecordNotFound {
@@ -251,12 +787,34 @@ func (m *Manager) GetNextEpisode(showID string) (*models.Episode, error) {
 
 func (m *Manager) GetUpcomingEpisodesForUser(userID int) ([]models.Episode, error) {
 	var episodes []models.Episode
-	thirtyDaysFromNow := time.Now().AddDate(0, 0, 30)
 
-	err := m.db.Joins("JOIN shows_bot.user_shows ON shows_bot.user_shows.show_id = shows_bot.episodes.show_id").
-		Where("shows_bot.user_shows.user_id = ? AND shows_bot.episodes.air_date > ? AND shows_bot.episodes.air_date < ?",
-			userID, time.Now(), thirtyDaysFromNow).
-		Order("shows_bot.episodes.air_date").
+	now := m.now()
+	thirtyDaysFromNow := now.AddDate(0, 0, 30)
+	userShows, episodesTable := m.table("user_shows"), m.table("episodes")
+	airTime := fmt.Sprintf("COALESCE(%[1]s.air_stamp, %[1]s.air_date)", episodesTable)
+
+	err := m.db.Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.show_id = %[2]s.show_id", userShows, episodesTable)).
+		Where(fmt.Sprintf("%[1]s.user_id = ? AND %[2]s > ? AND %[2]s < ?", userShows, airTime),
+			userID, now, thirtyDaysFromNow).
+		Order(airTime).
+		Find(&episodes).Error
+
+	return episodes, err
+}
+
+// GetCalendarEpisodes returns the episodes of every show the user follows, muted or not, airing at
+// or after from, ordered by air time, with their show loaded. Episodes without an air date are left out.
+func (m *Manager) GetCalendarEpisodes(userID int64, from time.Time) ([]models.Episode, error) {
+	var episodes []models.Episode
+
+	userShows, episodesTable := m.table("user_shows"), m.table("episodes")
+	airTime := fmt.Sprintf("COALESCE(%[1]s.air_stamp, %[1]s.air_date)", episodesTable)
+
+	err := m.db.Preload("Show").
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.show_id = %[2]s.show_id", userShows, episodesTable)).
+		Where(fmt.Sprintf("%[1]s.user_id = ? AND %[2]s >= ?", userShows, airTime), userID, from).
+		Order(airTime).
+		Order(episodesTable + ".id").
 		Find(&episodes).Error
 
 	return episodes, err
@@ -270,3 +828,49 @@ func (m *Manager) GetEpisodesForShow(showID string) ([]models.Episode, error) {
 
 	return episodes, err
 }
+
+// GetEpisode returns the episode with its show loaded.
+func (m *Manager) GetEpisode(id string) (*models.Episode, error) {
+	var episode models.Episode
+
+	result := m.db.Preload("Show").First(&episode, "id = ?", id)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return &episode, nil
+}
+
+// MarkEpisodesWatched records the episodes as watched by the user; already watched ones are kept as they are.
+func (m *Manager) MarkEpisodesWatched(userID int64, episodeIDs []string) error {
+	if len(episodeIDs) == 0 {
+		return nil
+	}
+
+	now := m.now()
+	watched := make([]models.WatchedEpisode, len(episodeIDs))
+
+	for i, episodeID := range episodeIDs {
+		watched[i] = models.WatchedEpisode{UserID: userID, EpisodeID: episodeID, WatchedAt: now}
+	}
+
+	return m.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&watched).Error
+}
+
+func (m *Manager) UnmarkEpisodeWatched(userID int64, episodeID string) error {
+	return m.db.Where("user_id = ? AND episode_id = ?", userID, episodeID).Delete(&models.WatchedEpisode{}).Error
+}
+
+// GetWatchedEpisodes returns the IDs of the show's episodes the user watched.
+func (m *Manager) GetWatchedEpisodes(userID int64, showID string) ([]string, error) {
+	watchedEpisodes, episodes := m.table("watched_episodes"), m.table("episodes")
+
+	var episodeIDs []string
+	err := m.db.Model(&models.WatchedEpisode{}).
+		Joins(fmt.Sprintf("JOIN %[1]s ON %[1]s.id = %[2]s.episode_id", episodes, watchedEpisodes)).
+		Where(fmt.Sprintf("%s.user_id = ? AND %s.show_id = ?", watchedEpisodes, episodes), userID, showID).
+		Order(watchedEpisodes+".episode_id").
+		Pluck(watchedEpisodes+".episode_id", &episodeIDs).Error
+
+	return episodeIDs, err
+}
@@ -0,0 +1,16 @@
+package database_test
+
+import (
+	"testing"
+
+	"github.com/dkhalizov/shows/internal/database/dbtest"
+)
+
+func TestSQLite(t *testing.T) {
+	dbtest.Run(t, dbtest.OpenSQLite)
+}
+
+// TestPostgres runs only when TEST_DATABASE_URL names a disposable database.
+func TestPostgres(t *testing.T) {
+	dbtest.Run(t, dbtest.OpenPostgres)
+}
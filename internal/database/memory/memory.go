@@ -0,0 +1,1156 @@
+// Package memory is an in-memory bot.Operations store for tests and local runs.
+//
+// It mirrors database.Manager, including its uniqueness and foreign key rules, so code
+// tested against it behaves the same on SQLite and Postgres. Missing rows are reported
+// with gorm.ErrRecordNotFound, as the Manager does.
+package memory
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+const upcomingWindowDays = 30
+
+var (
+	errUnknownUser    = errors.New("foreign key violation: unknown user")
+	errUnknownShow    = errors.New("foreign key violation: unknown show")
+	errUnknownEpisode = errors.New("foreign key violation: unknown episode")
+	errDuplicate      = errors.New("unique constraint violation")
+)
+
+type followKey struct {
+	userID int64
+	showID string
+}
+
+type watchedKey struct {
+	userID    int64
+	episodeID string
+}
+
+type notificationKey struct {
+	userID    int64
+	episodeID string
+	kind      string
+}
+
+// Store is a concurrency-safe in-memory bot.Operations. The zero value is not usable; call New.
+type Store struct {
+	mu sync.Mutex
+
+	users         map[int64]models.User
+	shows         map[string]models.Show
+	episodes      map[string]models.Episode
+	follows       map[followKey]models.UserShow
+	notifications map[notificationKey]models.Notification
+	settings      map[int64]models.UserSettings
+	watched       map[watchedKey]models.WatchedEpisode
+	changes       []models.EpisodeChange
+	outbox        []models.OutboxMessage // ordered by ID
+
+	lastNotificationID uint
+	lastChangeID       uint
+	lastOutboxID       uint
+}
+
+func New() *Store {
+	return &Store{
+		users:         make(map[int64]models.User),
+		shows:         make(map[string]models.Show),
+		episodes:      make(map[string]models.Episode),
+		follows:       make(map[followKey]models.UserShow),
+		notifications: make(map[notificationKey]models.Notification),
+		settings:      make(map[int64]models.UserSettings),
+		watched:       make(map[watchedKey]models.WatchedEpisode),
+	}
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func (s *Store) Init() error {
+	return nil
+}
+
+func (s *Store) Close() error {
+	return nil
+}
+
+// StoreUser inserts the user or refreshes their Telegram profile, keeping bot settings intact.
+func (s *Store) StoreUser(user models.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.users[user.ID]; ok {
+		existing.Username, existing.FirstName, existing.LastName = user.Username, user.FirstName, user.LastName
+		s.users[user.ID] = existing
+
+		return nil
+	}
+
+	user.Shows = nil
+	user.Active = true
+	user.CreatedAt = now()
+	s.users[user.ID] = user
+
+	return nil
+}
+
+func (s *Store) GetUser(userID int64) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &user, nil
+}
+
+func (s *Store) SetUserTimezone(userID int64, timezone string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	user.Timezone = timezone
+	s.users[userID] = user
+
+	return nil
+}
+
+// SetUserActive marks a user as reachable or not. Deactivating also stops their pending outbox messages.
+func (s *Store) SetUserActive(userID int64, active bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[userID]; ok && user.Active != active {
+		user.Active = active
+		user.DeactivatedAt = nil
+
+		if !active {
+			deactivatedAt := now()
+			user.DeactivatedAt = &deactivatedAt
+		}
+
+		s.users[userID] = user
+	}
+
+	if active {
+		return nil
+	}
+
+	for i, msg := range s.outbox {
+		if msg.ChatID == userID && msg.Status == models.OutboxPending {
+			s.outbox[i].Status = models.OutboxBlocked
+			s.outbox[i].LastError = "user deactivated"
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) SetCalendarToken(userID int64, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	for id, other := range s.users {
+		if id != userID && other.CalendarToken != nil && *other.CalendarToken == token {
+			return fmt.Errorf("%w: calendar token of user %d", errDuplicate, id)
+		}
+	}
+
+	user.CalendarToken = &token
+	s.users[userID] = user
+
+	return nil
+}
+
+func (s *Store) GetUserByCalendarToken(token string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.CalendarToken != nil && *user.CalendarToken == token {
+			return &user, nil
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (s *Store) SetNotifyChat(userID int64, chatID *int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	if chatID != nil {
+		id := *chatID
+		chatID = &id
+	}
+
+	user.NotifyChatID = chatID
+	s.users[userID] = user
+
+	return nil
+}
+
+func (s *Store) ClearNotifyChat(chatID int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var cleared int64
+
+	for id, user := range s.users {
+		if user.NotifyChatID != nil && *user.NotifyChatID == chatID {
+			user.NotifyChatID = nil
+			s.users[id] = user
+			cleared++
+		}
+	}
+
+	return cleared, nil
+}
+
+// StoreShow returns the ID of the stored show with the same IMDb ID or provider ID, backfilling
+// a missing IMDb ID, and inserts the show otherwise.
+func (s *Store) StoreShow(show *models.Show) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if show.IMDbID != "" {
+		for _, id := range s.showIDs() {
+			if s.shows[id].IMDbID == show.IMDbID {
+				return id, nil
+			}
+		}
+	}
+
+	for _, id := range s.showIDs() {
+		existing := s.shows[id]
+		if existing.Provider != show.Provider || existing.ProviderID != show.ProviderID {
+			continue
+		}
+
+		if show.IMDbID != "" && (existing.IMDbID == "" || existing.IMDbID == "0") {
+			existing.IMDbID = show.IMDbID
+			s.shows[id] = existing
+		}
+
+		return id, nil
+	}
+
+	if show.ID == "" {
+		show.ID = show.GenerateID()
+	}
+
+	if _, ok := s.shows[show.ID]; ok {
+		return "", fmt.Errorf("%w: show %s", errDuplicate, show.ID)
+	}
+
+	show.CreatedAt = now()
+
+	stored := *show
+	stored.Episodes, stored.Users = nil, nil
+	s.shows[show.ID] = stored
+
+	return show.ID, nil
+}
+
+// showIDs returns the stored show IDs in a stable order.
+func (s *Store) showIDs() []string {
+	ids := make([]string, 0, len(s.shows))
+	for id := range s.shows {
+		ids = append(ids, id)
+	}
+
+	slices.Sort(ids)
+
+	return ids
+}
+
+func (s *Store) GetShow(id string) (*models.Show, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	show, ok := s.shows[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	return &show, nil
+}
+
+func (s *Store) GetShowByIMDbID(imdbID string) (*models.Show, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if imdbID != "" {
+		for _, id := range s.showIDs() {
+			if show := s.shows[id]; show.IMDbID == imdbID {
+				return &show, nil
+			}
+		}
+	}
+
+	return nil, gorm.ErrRecordNotFound
+}
+
+// UpdateShowDetails overwrites the stored show with freshly fetched provider details and
+// returns the status it had before. Empty overview, poster and IMDb values keep the stored ones.
+func (s *Store) UpdateShowDetails(details *models.Show) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	show, ok := s.shows[details.ID]
+	if !ok {
+		return "", fmt.Errorf("error updating show %s: %w", details.ID, gorm.ErrRecordNotFound)
+	}
+
+	oldStatus := show.Status
+	show.RefreshedAt = now()
+
+	if details.Status != "" {
+		show.Status = details.Status
+	}
+
+	if details.Name != "" {
+		show.Name = details.Name
+	}
+
+	if !details.FirstAirDate.IsZero() {
+		show.FirstAirDate = details.FirstAirDate
+	}
+
+	if details.Overview != "" {
+		show.Overview = details.Overview
+	}
+
+	if details.PosterURL != "" {
+		show.PosterURL = details.PosterURL
+	}
+
+	if details.IMDbID != "" && details.IMDbID != "0" {
+		show.IMDbID = details.IMDbID
+	}
+
+	s.shows[show.ID] = show
+
+	return oldStatus, nil
+}
+
+// FollowShow subscribes the user to the show; following a show twice keeps the first subscription.
+func (s *Store) FollowShow(userID int, showID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := followKey{int64(userID), showID}
+	if _, ok := s.follows[key]; ok {
+		return nil
+	}
+
+	if _, ok := s.users[key.userID]; !ok {
+		return fmt.Errorf("%w %d", errUnknownUser, userID)
+	}
+
+	if _, ok := s.shows[showID]; !ok {
+		return fmt.Errorf("%w %s", errUnknownShow, showID)
+	}
+
+	s.follows[key] = models.UserShow{UserID: key.userID, ShowID: showID, CreatedAt: now()}
+
+	return nil
+}
+
+func (s *Store) UnfollowShow(userID int, showID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.follows, followKey{int64(userID), showID})
+
+	return nil
+}
+
+func (s *Store) IsUserFollowingShow(userID int, showID string) (bool, error) {
+	return s.IsShowFollowed(int64(userID), showID)
+}
+
+func (s *Store) IsShowFollowed(userID int64, showID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.follows[followKey{userID, showID}]
+
+	return ok, nil
+}
+
+func (s *Store) GetUserShows(userID int) ([]models.Show, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var shows []models.Show
+
+	for key := range s.follows {
+		if key.userID == int64(userID) {
+			shows = append(shows, s.shows[key.showID])
+		}
+	}
+
+	slices.SortFunc(shows, func(a, b models.Show) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+	})
+
+	return shows, nil
+}
+
+// GetAllFollowedShows returns the shows followed by at least one active user.
+func (s *Store) GetAllFollowedShows() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var showIDs []string
+
+	for key := range s.follows {
+		if s.users[key.userID].Active && !slices.Contains(showIDs, key.showID) {
+			showIDs = append(showIDs, key.showID)
+		}
+	}
+
+	slices.Sort(showIDs)
+
+	return showIDs, nil
+}
+
+// followers returns the subscriptions to showID ordered by user.
+func (s *Store) followers(showID string) []models.UserShow {
+	var follows []models.UserShow
+
+	for key, follow := range s.follows {
+		if key.showID == showID {
+			follows = append(follows, follow)
+		}
+	}
+
+	slices.SortFunc(follows, func(a, b models.UserShow) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+
+	return follows
+}
+
+// GetUsersToNotify returns the followers of the episode's show who have a reminder due now,
+// skipping muted shows, watched episodes and reminder kinds already sent. Users without settings get a single
+// reminder within defaultLead of the air time.
+func (s *Store) GetUsersToNotify(episode *models.Episode, defaultLead time.Duration) ([]models.Reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sent := make(map[int64]map[string]bool)
+
+	for key := range s.notifications {
+		if key.episodeID != episode.ID {
+			continue
+		}
+
+		if sent[key.userID] == nil {
+			sent[key.userID] = make(map[string]bool)
+		}
+
+		sent[key.userID][key.kind] = true
+	}
+
+	current := now()
+
+	var reminders []models.Reminder
+
+	for _, follow := range s.followers(episode.ShowID) {
+		user := s.users[follow.UserID]
+		if follow.Muted || !user.Active {
+			continue
+		}
+
+		if _, watched := s.watched[watchedKey{follow.UserID, episode.ID}]; watched {
+			continue
+		}
+
+		// Like the Manager's outer join, a user without stored settings has zero-valued ones.
+		settings, ok := s.settings[follow.UserID]
+		if !ok {
+			settings = models.UserSettings{UserID: follow.UserID}
+		}
+
+		kinds := settings.DueReminders(episode.AirTime(), current, user.Location(), defaultLead, sent[follow.UserID])
+		if len(kinds) > 0 {
+			reminders = append(reminders, models.Reminder{UserID: follow.UserID, Kinds: kinds})
+		}
+	}
+
+	return reminders, nil
+}
+
+// addNotification records a sent notification; the caller holds the lock.
+func (s *Store) addNotification(userID int64, episodeID, kind string, notifiedAt time.Time) error {
+	key := notificationKey{userID, episodeID, kind}
+	if _, ok := s.notifications[key]; ok {
+		return fmt.Errorf("%w: notification %s for user %d on %s", errDuplicate, kind, userID, episodeID)
+	}
+
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("%w %d", errUnknownUser, userID)
+	}
+
+	if _, ok := s.episodes[episodeID]; !ok {
+		return fmt.Errorf("%w %s", errUnknownEpisode, episodeID)
+	}
+
+	s.lastNotificationID++
+	s.notifications[key] = models.Notification{
+		ID:         s.lastNotificationID,
+		UserID:     userID,
+		EpisodeID:  episodeID,
+		Kind:       kind,
+		NotifiedAt: notifiedAt,
+		CreatedAt:  notifiedAt,
+	}
+
+	return nil
+}
+
+// checkNotifications reports the error addNotification would return for any of kinds,
+// so that multi-row writes fail before changing anything.
+func (s *Store) checkNotifications(userID int64, episodeIDs, kinds []string) error {
+	seen := make(map[notificationKey]bool)
+
+	for _, episodeID := range episodeIDs {
+		for _, kind := range kinds {
+			key := notificationKey{userID, episodeID, kind}
+
+			_, stored := s.notifications[key]
+			if stored || seen[key] {
+				return fmt.Errorf("%w: notification %s for user %d on %s", errDuplicate, kind, userID, episodeID)
+			}
+
+			seen[key] = true
+
+			if _, ok := s.episodes[episodeID]; !ok {
+				return fmt.Errorf("%w %s", errUnknownEpisode, episodeID)
+			}
+		}
+	}
+
+	if _, ok := s.users[userID]; !ok && len(seen) > 0 {
+		return fmt.Errorf("%w %d", errUnknownUser, userID)
+	}
+
+	return nil
+}
+
+func (s *Store) RecordNotification(userID int64, episodeID, kind string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addNotification(userID, episodeID, kind, now())
+}
+
+// GetShowFollowers returns the active users who follow showID without muting it.
+func (s *Store) GetShowFollowers(showID string) ([]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var userIDs []int64
+
+	for _, follow := range s.followers(showID) {
+		if follow.Muted || !s.users[follow.UserID].Active {
+			continue
+		}
+
+		userIDs = append(userIDs, follow.UserID)
+	}
+
+	return userIDs, nil
+}
+
+// ClearNotifications forgets sent reminders of the given kinds for an episode so they go out again
+// for a new air date. Users who watched the episode keep theirs.
+func (s *Store) ClearNotifications(episodeID string, kinds []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.notifications {
+		if key.episodeID != episodeID || !slices.Contains(kinds, key.kind) {
+			continue
+		}
+
+		if _, watched := s.watched[watchedKey{key.userID, episodeID}]; !watched {
+			delete(s.notifications, key)
+		}
+	}
+
+	return nil
+}
+
+// GetUserSettings returns the user's notification preferences, or defaults if they never changed them.
+func (s *Store) GetUserSettings(userID int64) (*models.UserSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings, ok := s.settings[userID]
+	if !ok {
+		settings = models.DefaultUserSettings(userID)
+	}
+
+	return &settings, nil
+}
+
+// SaveUserSettings stores the user's preferences; the last digest time is only set by RecordDigest.
+func (s *Store) SaveUserSettings(settings *models.UserSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[settings.UserID]; !ok {
+		return fmt.Errorf("%w %d", errUnknownUser, settings.UserID)
+	}
+
+	settings.UpdatedAt = now()
+
+	stored := *settings
+	if existing, ok := s.settings[settings.UserID]; ok {
+		stored.LastDigestAt = existing.LastDigestAt
+	}
+
+	s.settings[settings.UserID] = stored
+
+	return nil
+}
+
+func (s *Store) SetShowMuted(userID int64, showID string, muted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := followKey{userID, showID}
+
+	follow, ok := s.follows[key]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	follow.Muted = muted
+	s.follows[key] = follow
+
+	return nil
+}
+
+func (s *Store) GetMutedShows(userID int64) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var showIDs []string
+
+	for key, follow := range s.follows {
+		if key.userID == userID && follow.Muted {
+			showIDs = append(showIDs, key.showID)
+		}
+	}
+
+	slices.Sort(showIDs)
+
+	return showIDs, nil
+}
+
+func (s *Store) GetDigestSubscribers() ([]models.UserSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var subscribers []models.UserSettings
+
+	for userID, settings := range s.settings {
+		if settings.Digest != models.DigestOff && s.users[userID].Active {
+			subscribers = append(subscribers, settings)
+		}
+	}
+
+	slices.SortFunc(subscribers, func(a, b models.UserSettings) int {
+		return cmp.Compare(a.UserID, b.UserID)
+	})
+
+	return subscribers, nil
+}
+
+// GetEpisodesForDigest returns episodes of the user's unmuted shows airing in [from, to)
+// that no earlier digest listed and the user has not watched, ordered by air time, with their show loaded.
+func (s *Store) GetEpisodesForDigest(userID int64, from, to time.Time) ([]models.Episode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var episodes []models.Episode
+
+	for _, episode := range s.episodes {
+		follow, ok := s.follows[followKey{userID, episode.ShowID}]
+		if !ok || follow.Muted {
+			continue
+		}
+
+		airTime := episode.AirTime()
+		if airTime.Before(from) || !airTime.Before(to) {
+			continue
+		}
+
+		if _, listed := s.notifications[notificationKey{userID, episode.ID, models.KindDigest}]; listed {
+			continue
+		}
+
+		if _, watched := s.watched[watchedKey{userID, episode.ID}]; watched {
+			continue
+		}
+
+		episode = storedEpisode(episode)
+		episode.Show = s.shows[episode.ShowID]
+		episodes = append(episodes, episode)
+	}
+
+	sortByAirTime(episodes)
+
+	return episodes, nil
+}
+
+// RecordDigest marks the episodes as sent in a digest, enqueues the digest message
+// (nil when there was nothing to send) and stamps the user's last digest time, all or nothing.
+func (s *Store) RecordDigest(userID int64, episodeIDs []string, msg *models.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkNotifications(userID, episodeIDs, []string{models.KindDigest}); err != nil {
+		return err
+	}
+
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("%w %d", errUnknownUser, userID)
+	}
+
+	current := now()
+
+	for _, episodeID := range episodeIDs {
+		if err := s.addNotification(userID, episodeID, models.KindDigest, current); err != nil {
+			return err
+		}
+	}
+
+	if msg != nil {
+		s.enqueue(msg)
+	}
+
+	settings, ok := s.settings[userID]
+	if !ok {
+		settings = models.DefaultUserSettings(userID)
+		settings.UpdatedAt = current
+	}
+
+	settings.LastDigestAt = &current
+	s.settings[userID] = settings
+
+	return nil
+}
+
+// StoreEpisode inserts a new episode or updates the stored copy with the provider's
+// latest data. Air date and name changes are recorded and returned; otherwise the change is nil.
+func (s *Store) StoreEpisode(episode *models.Episode) (*models.EpisodeChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if episode.ID == "" {
+		episode.ID = fmt.Sprintf("%s_%s", episode.Provider, episode.ProviderID)
+	}
+
+	current := now()
+
+	existing, ok := s.episodes[episode.ID]
+	if !ok {
+		if _, ok = s.shows[episode.ShowID]; !ok {
+			return nil, fmt.Errorf("%w %s", errUnknownShow, episode.ShowID)
+		}
+
+		episode.CreatedAt, episode.UpdatedAt = current, current
+		s.episodes[episode.ID] = storedEpisode(*episode)
+
+		return nil, nil
+	}
+
+	// An air time becoming known for the same date is new detail, not a reschedule.
+	rescheduled := !existing.AirDate.Equal(episode.AirDate) ||
+		existing.HasAirTime() && episode.HasAirTime() && !existing.AirStamp.Equal(*episode.AirStamp)
+
+	if existing.Name == episode.Name &&
+		!rescheduled &&
+		existing.HasAirTime() == episode.HasAirTime() &&
+		existing.Overview == episode.Overview &&
+		existing.SeasonNumber == episode.SeasonNumber &&
+		existing.EpisodeNumber == episode.EpisodeNumber {
+		return nil, nil
+	}
+
+	var change *models.EpisodeChange
+	if existing.Name != episode.Name || rescheduled {
+		s.lastChangeID++
+		change = &models.EpisodeChange{
+			ID:         s.lastChangeID,
+			EpisodeID:  episode.ID,
+			OldAirDate: existing.AirTime(),
+			NewAirDate: existing.AirTime(),
+			OldName:    existing.Name,
+			NewName:    episode.Name,
+			ChangedAt:  current,
+
+			OldHasAirTime: existing.HasAirTime(),
+		}
+
+		if rescheduled {
+			change.NewAirDate = episode.AirTime()
+		}
+
+		s.changes = append(s.changes, *change)
+	}
+
+	existing.Name = episode.Name
+	existing.AirDate = episode.AirDate
+	existing.AirStamp = episode.AirStamp
+	existing.Overview = episode.Overview
+	existing.SeasonNumber = episode.SeasonNumber
+	existing.EpisodeNumber = episode.EpisodeNumber
+	existing.UpdatedAt = current
+	s.episodes[episode.ID] = storedEpisode(existing)
+
+	return change, nil
+}
+
+// storedEpisode drops associations and copies the air stamp so callers cannot change stored data.
+func storedEpisode(episode models.Episode) models.Episode {
+	episode.Show = models.Show{}
+	episode.Notifications = nil
+
+	if episode.AirStamp != nil {
+		stamp := *episode.AirStamp
+		episode.AirStamp = &stamp
+	}
+
+	return episode
+}
+
+func (s *Store) GetEpisodeChanges(episodeID string) ([]models.EpisodeChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var changes []models.EpisodeChange
+
+	for _, change := range s.changes {
+		if change.EpisodeID == episodeID {
+			changes = append(changes, change)
+		}
+	}
+
+	slices.SortStableFunc(changes, func(a, b models.EpisodeChange) int {
+		return a.ChangedAt.Compare(b.ChangedAt)
+	})
+
+	return changes, nil
+}
+
+func (s *Store) GetNextEpisode(showID string) (*models.Episode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := now()
+
+	var next *models.Episode
+
+	for _, episode := range s.showEpisodes(showID) {
+		if episode.AirTime().After(current) {
+			next = &episode
+
+			break
+		}
+	}
+
+	return next, nil
+}
+
+func (s *Store) GetUpcomingEpisodesForUser(userID int) ([]models.Episode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := now()
+	until := current.AddDate(0, 0, upcomingWindowDays)
+
+	var episodes []models.Episode
+
+	for _, episode := range s.episodes {
+		if _, ok := s.follows[followKey{int64(userID), episode.ShowID}]; !ok {
+			continue
+		}
+
+		if airTime := episode.AirTime(); airTime.After(current) && airTime.Before(until) {
+			episodes = append(episodes, storedEpisode(episode))
+		}
+	}
+
+	sortByAirTime(episodes)
+
+	return episodes, nil
+}
+
+// GetCalendarEpisodes returns the episodes of every show the user follows, muted or not, airing at
+// or after from, ordered by air time, with their show loaded. Episodes without an air date are left out.
+func (s *Store) GetCalendarEpisodes(userID int64, from time.Time) ([]models.Episode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var episodes []models.Episode
+
+	for _, episode := range s.episodes {
+		if _, ok := s.follows[followKey{userID, episode.ShowID}]; !ok {
+			continue
+		}
+
+		if episode.AirTime().Before(from) {
+			continue
+		}
+
+		episode = storedEpisode(episode)
+		episode.Show = s.shows[episode.ShowID]
+		episodes = append(episodes, episode)
+	}
+
+	sortByAirTime(episodes)
+
+	return episodes, nil
+}
+
+func (s *Store) GetEpisodesForShow(showID string) ([]models.Episode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var episodes []models.Episode
+
+	for _, episode := range s.episodes {
+		if episode.ShowID == showID {
+			episodes = append(episodes, storedEpisode(episode))
+		}
+	}
+
+	slices.SortFunc(episodes, func(a, b models.Episode) int {
+		return cmp.Or(
+			cmp.Compare(a.SeasonNumber, b.SeasonNumber),
+			cmp.Compare(a.EpisodeNumber, b.EpisodeNumber),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+
+	return episodes, nil
+}
+
+// GetEpisode returns the episode with its show loaded.
+func (s *Store) GetEpisode(id string) (*models.Episode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.episodes[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	episode := storedEpisode(stored)
+	episode.Show = s.shows[episode.ShowID]
+
+	return &episode, nil
+}
+
+// showEpisodes returns copies of the show's episodes ordered by air time.
+func (s *Store) showEpisodes(showID string) []models.Episode {
+	var episodes []models.Episode
+
+	for _, episode := range s.episodes {
+		if episode.ShowID == showID {
+			episodes = append(episodes, storedEpisode(episode))
+		}
+	}
+
+	sortByAirTime(episodes)
+
+	return episodes
+}
+
+func sortByAirTime(episodes []models.Episode) {
+	slices.SortFunc(episodes, func(a, b models.Episode) int {
+		return cmp.Or(a.AirTime().Compare(b.AirTime()), cmp.Compare(a.ID, b.ID))
+	})
+}
+
+// enqueue appends msg as a pending message due now; the caller holds the lock.
+func (s *Store) enqueue(msg *models.OutboxMessage) {
+	current := now()
+
+	s.lastOutboxID++
+	msg.ID = s.lastOutboxID
+	msg.Status = models.OutboxPending
+	msg.NextAttemptAt = current
+	msg.CreatedAt = current
+
+	s.outbox = append(s.outbox, *msg)
+}
+
+// EnqueueMessage queues a message for the outbox dispatcher.
+func (s *Store) EnqueueMessage(msg *models.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enqueue(msg)
+
+	return nil
+}
+
+// EnqueueNotification records the user's episode reminder kinds and queues its message together,
+// so a reminder is either both claimed and queued or neither.
+func (s *Store) EnqueueNotification(userID int64, msg *models.OutboxMessage, episodeID string, kinds []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkNotifications(userID, []string{episodeID}, kinds); err != nil {
+		return err
+	}
+
+	current := now()
+
+	for _, kind := range kinds {
+		if err := s.addNotification(userID, episodeID, kind, current); err != nil {
+			return err
+		}
+	}
+
+	s.enqueue(msg)
+
+	return nil
+}
+
+// GetDueOutboxMessages returns up to limit pending messages whose next attempt is due, oldest first.
+func (s *Store) GetDueOutboxMessages(limit int) ([]models.OutboxMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := now()
+
+	var messages []models.OutboxMessage
+
+	for _, msg := range s.outbox {
+		if limit >= 0 && len(messages) >= limit {
+			break
+		}
+
+		if msg.Status == models.OutboxPending && !msg.NextAttemptAt.After(current) {
+			messages = append(messages, msg)
+		}
+	}
+
+	return messages, nil
+}
+
+// UpdateOutboxMessage stores the outcome of a delivery attempt.
+func (s *Store) UpdateOutboxMessage(msg *models.OutboxMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, found := slices.BinarySearchFunc(s.outbox, msg.ID, func(stored models.OutboxMessage, id uint) int {
+		return cmp.Compare(stored.ID, id)
+	})
+	if !found {
+		return nil
+	}
+
+	stored := &s.outbox[i]
+	stored.Status = msg.Status
+	stored.Attempts = msg.Attempts
+	stored.NextAttemptAt = msg.NextAttemptAt
+	stored.LastError = msg.LastError
+	stored.SentAt = msg.SentAt
+
+	return nil
+}
+
+// MarkEpisodesWatched records the episodes as watched by the user; already watched ones are kept as they are.
+func (s *Store) MarkEpisodesWatched(userID int64, episodeIDs []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(episodeIDs) == 0 {
+		return nil
+	}
+
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("%w %d", errUnknownUser, userID)
+	}
+
+	for _, episodeID := range episodeIDs {
+		if _, ok := s.episodes[episodeID]; !ok {
+			return fmt.Errorf("%w %s", errUnknownEpisode, episodeID)
+		}
+	}
+
+	current := now()
+
+	for _, episodeID := range episodeIDs {
+		key := watchedKey{userID, episodeID}
+		if _, ok := s.watched[key]; !ok {
+			s.watched[key] = models.WatchedEpisode{UserID: userID, EpisodeID: episodeID, WatchedAt: current}
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) UnmarkEpisodeWatched(userID int64, episodeID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.watched, watchedKey{userID, episodeID})
+
+	return nil
+}
+
+// GetWatchedEpisodes returns the IDs of the show's episodes the user watched.
+func (s *Store) GetWatchedEpisodes(userID int64, showID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var episodeIDs []string
+
+	for key := range s.watched {
+		if key.userID == userID && s.episodes[key.episodeID].ShowID == showID {
+			episodeIDs = append(episodeIDs, key.episodeID)
+		}
+	}
+
+	slices.Sort(episodeIDs)
+
+	return episodeIDs, nil
+}
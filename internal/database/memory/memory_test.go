@@ -0,0 +1,11 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/dkhalizov/shows/internal/database/dbtest"
+)
+
+func TestMemory(t *testing.T) {
+	dbtest.Run(t, dbtest.OpenMemory)
+}
@@ -0,0 +1,297 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/dkhalizov/shows/migrations"
+)
+
+const migrationsTable = "schema_migrations"
+
+// ErrDirtySchema is returned when a previous migration failed part-way.
+// The schema has to be repaired by hand and the dirty row removed from schema_migrations.
+var ErrDirtySchema = errors.New("database schema is dirty")
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+type schemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	Dirty     bool   `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+// loadMigrations reads NNNN_name.up.sql / NNNN_name.down.sql pairs for dialect, ordered by version.
+func loadMigrations(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s migrations: %w", dialect, err)
+	}
+
+	byVersion := make(map[int]*Migration)
+
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		prefix, name, ok := strings.Cut(strings.TrimSuffix(fileName, "."+direction+".sql"), "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", fileName)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q: %w", fileName, err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(dialect, fileName))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", fileName, err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+
+		if direction == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up script", migration.Version, migration.Name)
+		}
+
+		result = append(result, *migration)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+func (m *Manager) migrationsTable() string {
+	return m.table(migrationsTable)
+}
+
+func (m *Manager) ensureMigrationsTable() error {
+	if m.dialect == dialectPostgres {
+		if err := m.db.Exec("CREATE SCHEMA IF NOT EXISTS " + postgresSchema).Error; err != nil {
+			return fmt.Errorf("failed to create schema: %w", err)
+		}
+	}
+
+	err := m.db.Exec("CREATE TABLE IF NOT EXISTS " + m.migrationsTable() + ` (
+		version    bigint    NOT NULL PRIMARY KEY,
+		name       text      NOT NULL,
+		dirty      boolean   NOT NULL,
+		applied_at timestamp NOT NULL
+	)`).Error
+	if err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Manager) appliedMigrations() (map[int]schemaMigration, error) {
+	if err := m.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Table(m.migrationsTable()).Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	applied := make(map[int]schemaMigration, len(rows))
+
+	for _, row := range rows {
+		if row.Dirty {
+			return nil, fmt.Errorf("%w: migration %04d_%s did not complete", ErrDirtySchema, row.Version, row.Name)
+		}
+
+		applied[row.Version] = row
+	}
+
+	return applied, nil
+}
+
+// MigrateUp applies all pending migrations in order and returns how many were applied.
+func (m *Manager) MigrateUp() (int, error) {
+	all, err := loadMigrations(migrations.FS, m.dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for _, migration := range all {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		slog.Info("Applying migration", "version", migration.Version, "name", migration.Name)
+
+		if err = m.runMigration(migration, migration.Up, true); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrateDown reverts the most recently applied steps migrations and returns how many were reverted.
+func (m *Manager) MigrateDown(steps int) (int, error) {
+	all, err := loadMigrations(migrations.FS, m.dialect)
+	if err != nil {
+		return 0, err
+	}
+
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+
+	for i := len(all) - 1; i >= 0 && count < steps; i-- {
+		migration := all[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+
+		if migration.Down == "" {
+			return count, fmt.Errorf("migration %04d_%s is irreversible", migration.Version, migration.Name)
+		}
+
+		slog.Info("Reverting migration", "version", migration.Version, "name", migration.Name)
+
+		if err = m.runMigration(migration, migration.Down, false); err != nil {
+			return count, err
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// MigrationStatus reports every known migration and whether it has been applied.
+func (m *Manager) MigrationStatus() ([]MigrationStatus, error) {
+	all, err := loadMigrations(migrations.FS, m.dialect)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	var rows []schemaMigration
+	if err = m.db.Table(m.migrationsTable()).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+
+	statuses := make([]MigrationStatus, 0, len(all))
+
+	for _, migration := range all {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+
+		if row, ok := applied[migration.Version]; ok {
+			status.Applied = !row.Dirty
+			status.Dirty = row.Dirty
+			status.AppliedAt = row.AppliedAt
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// runMigration marks the version dirty, then executes script and updates the
+// bookkeeping row in a single transaction. A failure leaves the dirty marker behind
+// so the next start refuses to run against a half-migrated schema.
+func (m *Manager) runMigration(migration Migration, script string, up bool) error {
+	table := m.migrationsTable()
+
+	marker := schemaMigration{Version: migration.Version, Name: migration.Name, Dirty: true, AppliedAt: time.Now()}
+	if err := m.db.Table(table).Save(&marker).Error; err != nil {
+		return fmt.Errorf("failed to mark migration %04d dirty: %w", migration.Version, err)
+	}
+
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		// Run through the raw connection so GORM doesn't treat '?' or '@' in the script as placeholders.
+		if _, err := tx.Statement.ConnPool.ExecContext(context.Background(), script); err != nil {
+			return err
+		}
+
+		if up {
+			return tx.Table(table).Where("version = ?", migration.Version).
+				Updates(map[string]any{"dirty": false, "applied_at": time.Now()}).Error
+		}
+
+		return tx.Table(table).Where("version = ?", migration.Version).Delete(&schemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("%w: migration %04d_%s failed: %w", ErrDirtySchema, migration.Version, migration.Name, err)
+	}
+
+	return nil
+}
@@ -8,13 +8,26 @@ import (
 	"gorm.io/gorm"
 )
 
+// User is a subscriber: a Telegram user, or a group chat that follows shows as a whole.
+// Group IDs are the (negative) chat IDs, so the same tables serve both.
 type User struct {
 	ID        int64 `gorm:"primaryKey"`
 	Username  string
 	FirstName string
 	LastName  string
+	Timezone  string    `gorm:"not null;default:''"` // IANA zone name, empty means UTC
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 
+	// Active is cleared when Telegram reports the user blocked the bot, and set again on /start.
+	Active        bool `gorm:"not null;default:true"`
+	DeactivatedAt *time.Time
+
+	// CalendarToken is the secret in the user's iCalendar feed URL; nil until /calendar is first used.
+	CalendarToken *string `gorm:"uniqueIndex"`
+
+	// NotifyChatID is the channel notifications are posted to instead of the subscriber's own chat.
+	NotifyChatID *int64
+
 	Shows []Show `gorm:"many2many:user_shows;"`
 }
 
@@ -27,6 +40,29 @@ func FromTelegramUser(tgUser *tgbotapi.User) User {
 	}
 }
 
+// FromTelegramChat is the subscriber for a group chat; its title stands in for a name.
+func FromTelegramChat(chat *tgbotapi.Chat) User {
+	return User{
+		ID:        chat.ID,
+		Username:  chat.UserName,
+		FirstName: chat.Title,
+	}
+}
+
+// Location returns the user's configured time zone, falling back to UTC.
+func (user User) Location() *time.Location {
+	if user.Timezone == "" {
+		return time.UTC
+	}
+
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+
+	return loc
+}
+
 type Show struct {
 	ID           string `gorm:"primaryKey"`
 	Name         string `gorm:"not null"`
@@ -36,8 +72,9 @@ type Show struct {
 	FirstAirDate time.Time
 	Provider     string    `gorm:"not null;index:idx_provider_id,priority:1"`
 	ProviderID   string    `gorm:"not null;index:idx_provider_id,priority:2;uniqueIndex:idx_provider_unique,priority:2"`
-	IMDbID       string    `gorm:"index"`
+	IMDbID       string    `gorm:"column:imdb_id;index"`
 	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	RefreshedAt  time.Time
 
 	Episodes []Episode `gorm:"foreignKey:ShowID"`
 	Users    []User    `gorm:"many2many:user_shows;"`
@@ -56,16 +93,18 @@ func (show Show) GenerateID() string {
 }
 
 type Episode struct {
-	ID            string `gorm:"primaryKey"`
-	ShowID        string `gorm:"not null;index"`
-	Name          string `gorm:"not null"`
-	SeasonNumber  int    `gorm:"not null"`
-	EpisodeNumber int    `gorm:"not null"`
-	AirDate       time.Time
-	Overview      string    `gorm:"type:text"`
-	Provider      string    `gorm:"not null;index:idx_episode_provider,priority:1"`
-	ProviderID    string    `gorm:"not null;index:idx_episode_provider,priority:2;uniqueIndex:idx_episode_provider_unique,priority:2"`
-	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	ID            string     `gorm:"primaryKey"`
+	ShowID        string     `gorm:"not null;index"`
+	Name          string     `gorm:"not null"`
+	SeasonNumber  int        `gorm:"not null"`
+	EpisodeNumber int        `gorm:"not null"`
+	AirDate       time.Time  // calendar date in the network's time zone, stored as UTC midnight
+	AirStamp      *time.Time // exact air time when the provider knows it
+	Overview      string     `gorm:"type:text"`
+	Provider      string     `gorm:"not null;index:idx_episode_provider,priority:1"`
+	ProviderID    string     `gorm:"not null;index:idx_episode_provider,priority:2;uniqueIndex:idx_episode_provider_unique,priority:2"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"autoUpdateTime"`
 
 	Show          Show           `gorm:"foreignKey:ShowID"`
 	Notifications []Notification `gorm:"foreignKey:EpisodeID"`
@@ -79,10 +118,46 @@ func (episode *Episode) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// AirTime returns the exact air time when known and the air date otherwise.
+func (episode Episode) AirTime() time.Time {
+	if episode.AirStamp != nil {
+		return *episode.AirStamp
+	}
+
+	return episode.AirDate
+}
+
+func (episode Episode) HasAirTime() bool {
+	return episode.AirStamp != nil
+}
+
+// EpisodeChange records a provider update to an episode's air date or name.
+type EpisodeChange struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	EpisodeID  string `gorm:"not null;index"`
+	OldAirDate time.Time
+	NewAirDate time.Time
+	OldName    string
+	NewName    string
+	ChangedAt  time.Time `gorm:"not null"`
+
+	// OldHasAirTime is set when OldAirDate was an exact air time rather than only a date.
+	OldHasAirTime bool `gorm:"not null"`
+}
+
+func (c EpisodeChange) Rescheduled() bool {
+	return !c.OldAirDate.Equal(c.NewAirDate)
+}
+
+func (c EpisodeChange) Renamed() bool {
+	return c.OldName != c.NewName
+}
+
 type Notification struct {
 	ID         uint      `gorm:"primaryKey;autoIncrement"`
 	UserID     int64     `gorm:"not null;uniqueIndex:idx_user_episode,priority:1"`
 	EpisodeID  string    `gorm:"not null;uniqueIndex:idx_user_episode,priority:2"`
+	Kind       string    `gorm:"not null;default:default;uniqueIndex:idx_user_episode,priority:3"`
 	NotifiedAt time.Time `gorm:"not null"`
 	CreatedAt  time.Time `gorm:"autoCreateTime"`
 
@@ -91,30 +166,11 @@ type Notification struct {
 }
 
 type UserShow struct {
-	UserID    int64     `gorm:"primaryKey;autoCreateTime"`
+	UserID    int64     `gorm:"primaryKey;autoIncrement:false"`
 	ShowID    string    `gorm:"primaryKey"`
+	Muted     bool      `gorm:"not null;default:false"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
 
 	User User `gorm:"foreignKey:UserID"`
 	Show Show `gorm:"foreignKey:ShowID"`
 }
-
-func (User) TableName() string {
-	return "shows_bot.users"
-}
-
-func (Show) TableName() string {
-	return "shows_bot.shows"
-}
-
-func (Episode) TableName() string {
-	return "shows_bot.episodes"
-}
-
-func (Notification) TableName() string {
-	return "shows_bot.notifications"
-}
-
-func (UserShow) TableName() string {
-	return "shows_bot.user_shows"
-}
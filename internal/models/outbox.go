@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Outbox message statuses. Pending messages are retried until they are sent or
+// reach a final status.
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+	OutboxBlocked = "blocked"
+)
+
+// OutboxMessage is a bot-initiated message queued for delivery. Rows are kept after
+// delivery as an audit trail of what was sent, when, and after how many attempts.
+type OutboxMessage struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	ChatID        int64     `gorm:"not null"`
+	Text          string    `gorm:"type:text;not null"`
+	ReplyMarkup   string    `gorm:"type:text;not null"` // JSON inline keyboard, empty for none
+	Status        string    `gorm:"not null;index:idx_outbox_messages_due,priority:1"`
+	Attempts      int       `gorm:"not null"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_outbox_messages_due,priority:2"`
+	LastError     string    `gorm:"not null"`
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	SentAt        *time.Time
+}
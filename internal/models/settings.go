@@ -0,0 +1,212 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Reminder kinds recorded in notifications.kind. KindDefault is the single reminder
+// sent to users who kept the bot-wide episode_notification_threshold.
+const (
+	KindDefault = "default"
+	KindWeek    = "1w"
+	KindDay     = "1d"
+	KindHour    = "1h"
+	KindOnAir   = "0"
+	KindDigest  = "digest"
+)
+
+const (
+	DigestOff    = ""
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// OnAirWindow is how long after an episode starts an "on air" reminder may still go out.
+const OnAirWindow = 6 * time.Hour
+
+type LeadTime struct {
+	Kind   string
+	Before time.Duration
+	Label  string
+}
+
+// LeadTimes lists the reminder lead times a user can pick, longest first.
+var LeadTimes = []LeadTime{
+	{Kind: KindWeek, Before: 7 * 24 * time.Hour, Label: "1 week before"},
+	{Kind: KindDay, Before: 24 * time.Hour, Label: "1 day before"},
+	{Kind: KindHour, Before: time.Hour, Label: "1 hour before"},
+	{Kind: KindOnAir, Before: 0, Label: "When it airs"},
+}
+
+func leadTimeOf(kind string) (LeadTime, bool) {
+	for _, lead := range LeadTimes {
+		if lead.Kind == kind {
+			return lead, true
+		}
+	}
+
+	return LeadTime{}, false
+}
+
+// UserSettings holds a user's notification preferences. The zero value means
+// bot defaults: one reminder within the global threshold and no quiet hours.
+type UserSettings struct {
+	UserID     int64  `gorm:"primaryKey;autoIncrement:false"`
+	LeadTimes  string `gorm:"not null"` // comma-separated reminder kinds, empty for the bot default
+	QuietHours bool   `gorm:"not null"`
+	QuietStart int    `gorm:"not null"` // hour of day in the user's time zone
+	QuietEnd   int    `gorm:"not null"`
+
+	Digest        string       `gorm:"not null"` // DigestOff, DigestDaily or DigestWeekly
+	DigestHour    int          `gorm:"not null"` // hour of day the digest is sent, in the user's time zone
+	DigestWeekday time.Weekday `gorm:"not null"` // day weekly digests are sent
+	LastDigestAt  *time.Time
+
+	UpdatedAt time.Time
+}
+
+// DefaultUserSettings returns the settings of a user who never changed them.
+func DefaultUserSettings(userID int64) UserSettings {
+	return UserSettings{UserID: userID, DigestHour: 8, DigestWeekday: time.Monday}
+}
+
+// Leads returns the reminders the user wants, longest lead first.
+func (s UserSettings) Leads(defaultLead time.Duration) []LeadTime {
+	if s.LeadTimes == "" {
+		return []LeadTime{{Kind: KindDefault, Before: defaultLead, Label: "Default"}}
+	}
+
+	var leads []LeadTime
+
+	for _, lead := range LeadTimes {
+		if s.HasLead(lead.Kind) {
+			leads = append(leads, lead)
+		}
+	}
+
+	return leads
+}
+
+// coveredByDigest reports whether a digest replaces this reminder: a digest
+// announces episodes a day or more ahead, so only shorter reminders still go out on their own.
+func coveredByDigest(lead LeadTime) bool {
+	return lead.Kind == KindDefault || lead.Before >= 24*time.Hour
+}
+
+func (s UserSettings) HasLead(kind string) bool {
+	for _, k := range strings.Split(s.LeadTimes, ",") {
+		if k == kind {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ToggleLead switches a reminder kind on or off. Turning off the last one restores the bot default.
+func (s *UserSettings) ToggleLead(kind string) {
+	if _, ok := leadTimeOf(kind); !ok {
+		return
+	}
+
+	enabled := s.HasLead(kind)
+
+	var kinds []string
+
+	for _, lead := range LeadTimes {
+		if lead.Kind == kind && !enabled || lead.Kind != kind && s.HasLead(lead.Kind) {
+			kinds = append(kinds, lead.Kind)
+		}
+	}
+
+	s.LeadTimes = strings.Join(kinds, ",")
+}
+
+// InQuietHours reports whether t, already in the user's zone, falls in their quiet hours.
+func (s UserSettings) InQuietHours(t time.Time) bool {
+	if !s.QuietHours || s.QuietStart == s.QuietEnd {
+		return false
+	}
+
+	hour := t.Hour()
+	if s.QuietStart < s.QuietEnd {
+		return hour >= s.QuietStart && hour < s.QuietEnd
+	}
+
+	return hour >= s.QuietStart || hour < s.QuietEnd
+}
+
+// DueReminders returns the reminder kinds for an episode airing at airTime that are due at now
+// and not yet in sent. Reminders falling in quiet hours wait until they end, and are dropped
+// once the episode has aired (or, for the on-air reminder, once OnAirWindow has passed).
+func (s UserSettings) DueReminders(
+	airTime, now time.Time,
+	loc *time.Location,
+	defaultLead time.Duration,
+	sent map[string]bool,
+) []string {
+	if airTime.IsZero() || s.InQuietHours(now.In(loc)) {
+		return nil
+	}
+
+	var due []string
+
+	for _, lead := range s.Leads(defaultLead) {
+		if sent[lead.Kind] {
+			continue
+		}
+
+		if (s.Digest != DigestOff || sent[KindDigest]) && coveredByDigest(lead) {
+			continue
+		}
+
+		end := airTime
+		if lead.Before == 0 {
+			end = airTime.Add(OnAirWindow)
+		}
+
+		if !now.Before(airTime.Add(-lead.Before)) && now.Before(end) {
+			due = append(due, lead.Kind)
+		}
+	}
+
+	return due
+}
+
+// DigestPeriod is how far ahead a digest looks.
+func (s UserSettings) DigestPeriod() time.Duration {
+	if s.Digest == DigestWeekly {
+		return 7 * 24 * time.Hour
+	}
+
+	return 24 * time.Hour
+}
+
+// DigestDue reports whether the user's digest should go out at now: its most recent
+// scheduled time in loc has passed and no digest was sent since.
+func (s UserSettings) DigestDue(now time.Time, loc *time.Location) bool {
+	if s.Digest == DigestOff {
+		return false
+	}
+
+	local := now.In(loc)
+	scheduled := time.Date(local.Year(), local.Month(), local.Day(), s.DigestHour, 0, 0, 0, loc)
+
+	if s.Digest == DigestWeekly {
+		scheduled = scheduled.AddDate(0, 0, -int((local.Weekday()-s.DigestWeekday+7)%7))
+	}
+
+	if scheduled.After(local) {
+		scheduled = scheduled.AddDate(0, 0, -int(s.DigestPeriod()/(24*time.Hour)))
+	}
+
+	return s.LastDigestAt == nil || s.LastDigestAt.Before(scheduled)
+}
+
+// Reminder is a notification due for one user; every kind in Kinds is recorded
+// once the single message is delivered, so overlapping lead times don't repeat it.
+type Reminder struct {
+	UserID int64
+	Kinds  []string
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// WatchedEpisode records that a user has seen an episode. Reminders are not sent for watched episodes.
+type WatchedEpisode struct {
+	UserID    int64     `gorm:"primaryKey;autoIncrement:false"`
+	EpisodeID string    `gorm:"primaryKey"`
+	WatchedAt time.Time `gorm:"not null"`
+}
+
+// Progress is how far a user got through a show. Specials do not count.
+type Progress struct {
+	Aired   int      // regular episodes aired so far
+	Watched int      // watched regular episodes, aired or not
+	Next    *Episode // first unwatched regular episode, nil when caught up
+}
+
+// Percent returns the share of aired episodes watched, capped at 100.
+func (p Progress) Percent() int {
+	if p.Aired == 0 {
+		return 0
+	}
+
+	return min(100, p.Watched*100/p.Aired)
+}
+
+// ShowProgress computes progress over a show's episodes in season order.
+func ShowProgress(episodes []Episode, watched map[string]bool, now time.Time) Progress {
+	var progress Progress
+
+	for i, episode := range episodes {
+		if episode.SeasonNumber == 0 {
+			continue
+		}
+
+		airTime := episode.AirTime()
+		if !airTime.IsZero() && !airTime.After(now) {
+			progress.Aired++
+		}
+
+		switch {
+		case watched[episode.ID]:
+			progress.Watched++
+		case progress.Next == nil:
+			progress.Next = &episodes[i]
+		}
+	}
+
+	return progress
+}
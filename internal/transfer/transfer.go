@@ -0,0 +1,277 @@
+// Package transfer reads and writes lists of followed shows: the bot's own JSON and CSV
+// exports, IMDb watchlist CSV exports and Trakt JSON exports.
+package transfer
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dkhalizov/shows/internal/models"
+)
+
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatIMDb   = "imdb"
+	FormatTrakt  = "trakt"
+	formatMarker = "shows_bot"
+	version      = 1
+)
+
+// ErrUnknownFormat is returned for files that are none of the supported exports.
+var ErrUnknownFormat = errors.New("unrecognized file format")
+
+var csvHeader = []string{"name", "provider", "provider_id", "imdb_id"}
+
+// imdbShowTypes are the IMDb title types that are TV shows; watchlists also hold movies and episodes.
+var imdbShowTypes = map[string]bool{
+	"tv series":      true,
+	"tv mini series": true,
+	"tvseries":       true,
+	"tvminiseries":   true,
+}
+
+// Show is one followed show in an export.
+type Show struct {
+	Name       string `json:"name"`
+	Provider   string `json:"provider"`
+	ProviderID string `json:"provider_id"`
+	IMDbID     string `json:"imdb_id,omitempty"`
+}
+
+type export struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	Shows      []Show    `json:"shows"`
+}
+
+// Entry is a show to follow read from an import file. Provider and ProviderID are set when
+// the file names the show at one of the bot's providers.
+type Entry struct {
+	Line       int // line of a CSV row or position in a JSON list, from 1
+	Title      string
+	Year       int
+	Provider   string
+	ProviderID string
+	IMDbID     string
+}
+
+// Label names the entry in import reports.
+func (e Entry) Label() string {
+	label := e.Title
+	if label == "" {
+		label = cmp.Or(e.IMDbID, e.Provider+" "+e.ProviderID)
+	}
+
+	if e.Year > 0 {
+		label += fmt.Sprintf(" (%d)", e.Year)
+	}
+
+	return label
+}
+
+// Import is the content of an import file.
+type Import struct {
+	Format  string
+	Entries []Entry
+	Skipped int // movies and other titles that are not shows
+}
+
+func fromModel(show models.Show) Show {
+	return Show{Name: show.Name, Provider: show.Provider, ProviderID: show.ProviderID, IMDbID: show.IMDbID}
+}
+
+// WriteJSON writes shows in the bot's JSON export format.
+func WriteJSON(w io.Writer, shows []models.Show, now time.Time) error {
+	doc := export{Format: formatMarker, Version: version, ExportedAt: now.UTC(), Shows: make([]Show, len(shows))}
+	for i, show := range shows {
+		doc.Shows[i] = fromModel(show)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(doc)
+}
+
+// WriteCSV writes shows in the bot's CSV export format.
+func WriteCSV(w io.Writer, shows []models.Show) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, show := range shows {
+		if err := writer.Write([]string{show.Name, show.Provider, show.ProviderID, show.IMDbID}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// Parse detects the format of data and reads the shows it lists.
+func Parse(data []byte) (*Import, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(trimmed) == 0:
+		return nil, fmt.Errorf("%w: the file is empty", ErrUnknownFormat)
+	case trimmed[0] == '{':
+		return parseJSON(trimmed)
+	case trimmed[0] == '[':
+		return parseTrakt(trimmed)
+	default:
+		return parseCSV(data)
+	}
+}
+
+func parseJSON(data []byte) (*Import, error) {
+	var doc export
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownFormat, err)
+	}
+
+	if doc.Format != formatMarker {
+		return nil, ErrUnknownFormat
+	}
+
+	result := &Import{Format: FormatJSON}
+
+	for i, show := range doc.Shows {
+		result.Entries = append(result.Entries, Entry{
+			Line:       i + 1,
+			Title:      show.Name,
+			Provider:   show.Provider,
+			ProviderID: show.ProviderID,
+			IMDbID:     show.IMDbID,
+		})
+	}
+
+	return result, nil
+}
+
+type traktIDs struct {
+	IMDb string `json:"imdb"`
+	TMDB int    `json:"tmdb"`
+}
+
+type traktShow struct {
+	Title string   `json:"title"`
+	Year  int      `json:"year"`
+	IDs   traktIDs `json:"ids"`
+}
+
+// traktItem is an element of a Trakt watchlist, watched or collection export; only show items are imported.
+type traktItem struct {
+	Show *traktShow `json:"show"`
+}
+
+func parseTrakt(data []byte) (*Import, error) {
+	var items []traktItem
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownFormat, err)
+	}
+
+	result := &Import{Format: FormatTrakt}
+
+	for i, item := range items {
+		if item.Show == nil {
+			result.Skipped++
+
+			continue
+		}
+
+		entry := Entry{Line: i + 1, Title: item.Show.Title, Year: item.Show.Year, IMDbID: item.Show.IDs.IMDb}
+		if item.Show.IDs.TMDB > 0 {
+			entry.Provider, entry.ProviderID = "tmdb", strconv.Itoa(item.Show.IDs.TMDB)
+		}
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+func parseCSV(data []byte) (*Import, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnknownFormat, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	_, hasProvider := columns["provider_id"]
+	_, hasConst := columns["const"]
+
+	var result *Import
+
+	switch {
+	case hasProvider:
+		result = &Import{Format: FormatCSV}
+	case hasConst:
+		result = &Import{Format: FormatIMDb}
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+
+			return ""
+		}
+
+		if result.Format == FormatCSV {
+			result.Entries = append(result.Entries, Entry{
+				Line:       line,
+				Title:      field("name"),
+				Provider:   field("provider"),
+				ProviderID: field("provider_id"),
+				IMDbID:     field("imdb_id"),
+			})
+
+			continue
+		}
+
+		if !imdbShowTypes[strings.ToLower(field("title type"))] {
+			result.Skipped++
+
+			continue
+		}
+
+		year, _ := strconv.Atoi(field("year"))
+		result.Entries = append(result.Entries, Entry{Line: line, Title: field("title"), Year: year, IMDbID: field("const")})
+	}
+}
@@ -0,0 +1,157 @@
+package transfer_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dkhalizov/shows/internal/models"
+	"github.com/dkhalizov/shows/internal/transfer"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    *transfer.Import
+		wantErr error
+	}{
+		{
+			name: "own json",
+			data: `{"format": "shows_bot", "version": 1, "exported_at": "2026-10-17T00:00:00Z", "shows": [
+				{"name": "Night Shift Detectives", "provider": "tvmaze", "provider_id": "1001", "imdb_id": "tt9000001"},
+				{"name": "Orbit Station", "provider": "tvmaze", "provider_id": "1003"}
+			]}`,
+			want: &transfer.Import{Format: transfer.FormatJSON, Entries: []transfer.Entry{
+				{Line: 1, Title: "Night Shift Detectives", Provider: "tvmaze", ProviderID: "1001", IMDbID: "tt9000001"},
+				{Line: 2, Title: "Orbit Station", Provider: "tvmaze", ProviderID: "1003"},
+			}},
+		},
+		{
+			name: "own csv with byte order mark",
+			data: "\xef\xbb\xbfname,provider,provider_id,imdb_id\nGlass Kingdom,tmdb,2002,tt9000004\n",
+			want: &transfer.Import{Format: transfer.FormatCSV, Entries: []transfer.Entry{
+				{Line: 2, Title: "Glass Kingdom", Provider: "tmdb", ProviderID: "2002", IMDbID: "tt9000004"},
+			}},
+		},
+		{
+			name: "trakt",
+			data: `[
+				{"type": "show", "show": {"title": "Glass Kingdom", "year": 2022, "ids": {"imdb": "tt9000004", "tmdb": 2002}}},
+				{"type": "movie", "movie": {"title": "Some Film", "year": 2020}},
+				{"type": "show", "show": {"title": "Harbor Lights", "year": 2015, "ids": {"imdb": "tt9000002"}}}
+			]`,
+			want: &transfer.Import{Format: transfer.FormatTrakt, Skipped: 1, Entries: []transfer.Entry{
+				{Line: 1, Title: "Glass Kingdom", Year: 2022, Provider: "tmdb", ProviderID: "2002", IMDbID: "tt9000004"},
+				{Line: 3, Title: "Harbor Lights", Year: 2015, IMDbID: "tt9000002"},
+			}},
+		},
+		{
+			name: "imdb csv",
+			data: "Position,Const,Created,Modified,Description,Title,URL,Title Type,Year\n" +
+				"1,tt9000001,2026-01-01,2026-01-01,,Night Shift Detectives,https://www.imdb.com/title/tt9000001/,TV Series,2021\n" +
+				"2,tt0000009,2026-01-01,2026-01-01,,Some Film,https://www.imdb.com/title/tt0000009/,Movie,2020\n" +
+				"3,tt9000003,2026-01-01,2026-01-01,\"Space, the show\",Orbit Station,https://www.imdb.com/title/tt9000003/,TV Mini Series,2019\n",
+			want: &transfer.Import{Format: transfer.FormatIMDb, Skipped: 1, Entries: []transfer.Entry{
+				{Line: 2, Title: "Night Shift Detectives", Year: 2021, IMDbID: "tt9000001"},
+				{Line: 4, Title: "Orbit Station", Year: 2019, IMDbID: "tt9000003"},
+			}},
+		},
+		{
+			name:    "empty",
+			data:    " \n\t",
+			wantErr: transfer.ErrUnknownFormat,
+		},
+		{
+			name:    "json from another tool",
+			data:    `{"format": "other", "shows": []}`,
+			wantErr: transfer.ErrUnknownFormat,
+		},
+		{
+			name:    "truncated json",
+			data:    `{"format": "shows_bot", "shows": [`,
+			wantErr: transfer.ErrUnknownFormat,
+		},
+		{
+			name:    "truncated trakt",
+			data:    `[{"show": {"title": "Glass Kingdom"`,
+			wantErr: transfer.ErrUnknownFormat,
+		},
+		{
+			name:    "csv without known columns",
+			data:    "title,rating\nGlass Kingdom,9\n",
+			wantErr: transfer.ErrUnknownFormat,
+		},
+		{
+			name:    "csv with broken quoting",
+			data:    "name,provider,provider_id\n\"Glass Kingdom,tmdb,2002\n",
+			wantErr: csv.ErrQuote,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transfer.Parse([]byte(tt.data))
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportsParseBack(t *testing.T) {
+	shows := []models.Show{
+		{Name: "Night Shift Detectives", Provider: "tvmaze", ProviderID: "1001", IMDbID: "tt9000001"},
+		{Name: "Glass, Kingdom", Provider: "tmdb", ProviderID: "2002"},
+	}
+
+	want := []transfer.Entry{
+		{Title: "Night Shift Detectives", Provider: "tvmaze", ProviderID: "1001", IMDbID: "tt9000001"},
+		{Title: "Glass, Kingdom", Provider: "tmdb", ProviderID: "2002"},
+	}
+
+	var jsonOut, csvOut bytes.Buffer
+
+	if err := transfer.WriteJSON(&jsonOut, shows, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := transfer.WriteCSV(&csvOut, shows); err != nil {
+		t.Fatal(err)
+	}
+
+	for format, data := range map[string][]byte{transfer.FormatJSON: jsonOut.Bytes(), transfer.FormatCSV: csvOut.Bytes()} {
+		got, err := transfer.Parse(data)
+		if err != nil {
+			t.Fatalf("Parse(%s export) error = %v", format, err)
+		}
+
+		if got.Format != format {
+			t.Errorf("format = %s, want %s", got.Format, format)
+		}
+
+		for i := range got.Entries {
+			got.Entries[i].Line = 0
+		}
+
+		if !reflect.DeepEqual(got.Entries, want) {
+			t.Errorf("%s export parsed back as %+v, want %+v", format, got.Entries, want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+// Package migrations embeds the versioned SQL schema migrations.
+//
+// Each dialect has its own directory of NNNN_name.up.sql / NNNN_name.down.sql pairs.
+// Versions are shared between dialects so a given version means the same schema change everywhere.
+package migrations
+
+import "embed"
+
+//go:embed postgres/*.sql sqlite/*.sql
+var FS embed.FS
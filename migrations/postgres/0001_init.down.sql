@@ -0,0 +1,5 @@
+drop table if exists shows_bot.user_shows;
+drop table if exists shows_bot.notifications;
+drop table if exists shows_bot.users;
+drop table if exists shows_bot.episodes;
+drop table if exists shows_bot.shows;
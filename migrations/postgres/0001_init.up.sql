@@ -1,4 +1,8 @@
-create table shows_bot.shows
+-- Baseline schema. Written to be idempotent so databases created by the
+-- previous GORM AutoMigrate setup can adopt versioned migrations in place.
+create schema if not exists shows_bot;
+
+create table if not exists shows_bot.shows
 (
     id             text                    not null
         primary key,
@@ -14,10 +18,7 @@ create table shows_bot.shows
     unique (provider, provider_id)
 );
 
-alter table shows_bot.shows
-    owner to postgres;
-
-create table shows_bot.episodes
+create table if not exists shows_bot.episodes
 (
     id             text                    not null
         primary key,
@@ -35,14 +36,14 @@ create table shows_bot.episodes
     unique (provider, provider_id)
 );
 
-alter table shows_bot.episodes
-    owner to postgres;
-
-create index idx_shows_imdb_id
+create index if not exists idx_shows_imdb_id
     on shows_bot.shows (imdb_id)
     where (imdb_id IS NOT NULL);
 
-create table shows_bot.users
+create index if not exists idx_episodes_show_id
+    on shows_bot.episodes (show_id);
+
+create table if not exists shows_bot.users
 (
     id         bigint                  not null
         primary key,
@@ -52,10 +53,7 @@ create table shows_bot.users
     created_at timestamp default now() not null
 );
 
-alter table shows_bot.users
-    owner to postgres;
-
-create table shows_bot.notifications
+create table if not exists shows_bot.notifications
 (
     id          serial
         primary key,
@@ -65,15 +63,21 @@ create table shows_bot.notifications
     episode_id  text                    not null
         references shows_bot.episodes
             on delete cascade,
-    notified_at timestamp,
+    notified_at timestamp default now() not null,
     created_at  timestamp default now() not null,
     unique (user_id, episode_id)
 );
 
+-- Older hand-created schemas allowed NULL here while the model always sets it.
+update shows_bot.notifications
+set notified_at = created_at
+where notified_at is null;
+
 alter table shows_bot.notifications
-    owner to postgres;
+    alter column notified_at set default now(),
+    alter column notified_at set not null;
 
-create table shows_bot.user_shows
+create table if not exists shows_bot.user_shows
 (
     user_id    bigint                  not null
         references shows_bot.users
@@ -84,7 +88,3 @@ create table shows_bot.user_shows
     created_at timestamp default now() not null,
     primary key (user_id, show_id)
 );
-
-alter table shows_bot.user_shows
-    owner to postgres;
-
@@ -0,0 +1,4 @@
+drop table if exists shows_bot.episode_changes;
+
+alter table shows_bot.episodes
+    drop column if exists updated_at;
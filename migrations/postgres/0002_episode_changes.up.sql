@@ -0,0 +1,19 @@
+alter table shows_bot.episodes
+    add column if not exists updated_at timestamp default now() not null;
+
+create table if not exists shows_bot.episode_changes
+(
+    id           serial
+        primary key,
+    episode_id   text                    not null
+        references shows_bot.episodes
+            on delete cascade,
+    old_air_date timestamp,
+    new_air_date timestamp,
+    old_name     text,
+    new_name     text,
+    changed_at   timestamp default now() not null
+);
+
+create index if not exists idx_episode_changes_episode_id
+    on shows_bot.episode_changes (episode_id);
@@ -0,0 +1,2 @@
+alter table shows_bot.shows
+    drop column if exists refreshed_at;
@@ -0,0 +1,2 @@
+alter table shows_bot.shows
+    add column if not exists refreshed_at timestamp;
@@ -0,0 +1,5 @@
+alter table shows_bot.episodes
+    drop column if exists air_stamp;
+
+alter table shows_bot.users
+    drop column if exists timezone;
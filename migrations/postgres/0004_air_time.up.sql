@@ -0,0 +1,5 @@
+alter table shows_bot.users
+    add column if not exists timezone text default '' not null;
+
+alter table shows_bot.episodes
+    add column if not exists air_stamp timestamp;
@@ -0,0 +1,19 @@
+drop index if exists shows_bot.idx_notifications_user_episode_kind;
+
+delete
+from shows_bot.notifications n
+    using shows_bot.notifications earlier
+where n.user_id = earlier.user_id
+  and n.episode_id = earlier.episode_id
+  and n.id > earlier.id;
+
+alter table shows_bot.notifications
+    drop column if exists kind;
+
+alter table shows_bot.notifications
+    add constraint notifications_user_id_episode_id_key unique (user_id, episode_id);
+
+alter table shows_bot.user_shows
+    drop column if exists muted;
+
+drop table if exists shows_bot.user_settings;
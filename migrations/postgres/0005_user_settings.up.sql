@@ -0,0 +1,28 @@
+create table if not exists shows_bot.user_settings
+(
+    user_id     bigint                  not null
+        primary key
+        references shows_bot.users
+            on delete cascade,
+    lead_times  text      default ''    not null,
+    quiet_hours boolean   default false not null,
+    quiet_start integer   default 0     not null,
+    quiet_end   integer   default 0     not null,
+    updated_at  timestamp default now() not null
+);
+
+alter table shows_bot.user_shows
+    add column if not exists muted boolean default false not null;
+
+-- A user can now get several reminders per episode, one per kind.
+-- Existing rows are the single reminder everyone used to get.
+alter table shows_bot.notifications
+    add column if not exists kind text default 'default' not null;
+
+alter table shows_bot.notifications
+    drop constraint if exists notifications_user_id_episode_id_key;
+
+drop index if exists shows_bot.idx_user_episode;
+
+create unique index if not exists idx_notifications_user_episode_kind
+    on shows_bot.notifications (user_id, episode_id, kind);
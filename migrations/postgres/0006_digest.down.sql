@@ -0,0 +1,5 @@
+alter table shows_bot.user_settings
+    drop column if exists last_digest_at,
+    drop column if exists digest_weekday,
+    drop column if exists digest_hour,
+    drop column if exists digest;
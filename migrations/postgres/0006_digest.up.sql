@@ -0,0 +1,5 @@
+alter table shows_bot.user_settings
+    add column if not exists digest         text    default '' not null,
+    add column if not exists digest_hour    integer default 8  not null,
+    add column if not exists digest_weekday integer default 1  not null,
+    add column if not exists last_digest_at timestamp;
@@ -0,0 +1,17 @@
+create table if not exists shows_bot.outbox_messages
+(
+    id              bigserial
+        primary key,
+    chat_id         bigint                    not null,
+    text            text                      not null,
+    reply_markup    text      default ''      not null,
+    status          text      default 'pending' not null,
+    attempts        integer   default 0       not null,
+    next_attempt_at timestamp default now()   not null,
+    last_error      text      default ''      not null,
+    created_at      timestamp default now()   not null,
+    sent_at         timestamp
+);
+
+create index if not exists idx_outbox_messages_due
+    on shows_bot.outbox_messages (status, next_attempt_at);
@@ -0,0 +1,3 @@
+alter table shows_bot.users
+    drop column if exists deactivated_at,
+    drop column if exists active;
@@ -0,0 +1,3 @@
+alter table shows_bot.users
+    add column if not exists active         boolean default true not null,
+    add column if not exists deactivated_at timestamp;
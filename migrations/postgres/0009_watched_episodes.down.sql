@@ -0,0 +1 @@
+drop table if exists shows_bot.watched_episodes;
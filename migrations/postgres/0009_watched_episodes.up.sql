@@ -0,0 +1,11 @@
+create table if not exists shows_bot.watched_episodes
+(
+    user_id    bigint                  not null
+        references shows_bot.users
+            on delete cascade,
+    episode_id text                    not null
+        references shows_bot.episodes
+            on delete cascade,
+    watched_at timestamp default now() not null,
+    primary key (user_id, episode_id)
+);
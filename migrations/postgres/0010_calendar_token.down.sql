@@ -0,0 +1,4 @@
+drop index if exists shows_bot.idx_users_calendar_token;
+
+alter table shows_bot.users
+    drop column if exists calendar_token;
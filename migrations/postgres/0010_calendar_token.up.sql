@@ -0,0 +1,5 @@
+alter table shows_bot.users
+    add column if not exists calendar_token text;
+
+create unique index if not exists idx_users_calendar_token
+    on shows_bot.users (calendar_token);
@@ -0,0 +1,2 @@
+alter table shows_bot.users
+    drop column if exists notify_chat_id;
@@ -0,0 +1,2 @@
+alter table shows_bot.users
+    add column if not exists notify_chat_id bigint;
@@ -0,0 +1,2 @@
+alter table shows_bot.episode_changes
+    drop column if exists old_has_air_time;
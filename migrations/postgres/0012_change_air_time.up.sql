@@ -0,0 +1,2 @@
+alter table shows_bot.episode_changes
+    add column if not exists old_has_air_time boolean default false not null;
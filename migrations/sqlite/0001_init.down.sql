@@ -0,0 +1,5 @@
+drop table if exists user_shows;
+drop table if exists notifications;
+drop table if exists users;
+drop table if exists episodes;
+drop table if exists shows;
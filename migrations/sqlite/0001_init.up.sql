@@ -0,0 +1,77 @@
+create table if not exists shows
+(
+    id             text     not null
+        primary key,
+    name           text     not null,
+    overview       text,
+    poster_url     text,
+    status         text,
+    first_air_date datetime,
+    provider       text     not null,
+    provider_id    text     not null,
+    created_at     datetime default current_timestamp not null,
+    imdb_id        text,
+    unique (provider, provider_id)
+);
+
+create table if not exists episodes
+(
+    id             text     not null
+        primary key,
+    show_id        text     not null
+        references shows
+            on delete cascade,
+    name           text     not null,
+    season_number  integer  not null,
+    episode_number integer  not null,
+    air_date       datetime,
+    overview       text,
+    provider       text     not null,
+    provider_id    text     not null,
+    created_at     datetime default current_timestamp not null,
+    unique (provider, provider_id)
+);
+
+create index if not exists idx_shows_imdb_id
+    on shows (imdb_id)
+    where (imdb_id IS NOT NULL);
+
+create index if not exists idx_episodes_show_id
+    on episodes (show_id);
+
+create table if not exists users
+(
+    id         integer  not null
+        primary key,
+    username   text,
+    first_name text,
+    last_name  text,
+    created_at datetime default current_timestamp not null
+);
+
+create table if not exists notifications
+(
+    id          integer  not null
+        primary key autoincrement,
+    user_id     integer  not null
+        references users
+            on delete cascade,
+    episode_id  text     not null
+        references episodes
+            on delete cascade,
+    notified_at datetime default current_timestamp not null,
+    created_at  datetime default current_timestamp not null,
+    unique (user_id, episode_id)
+);
+
+create table if not exists user_shows
+(
+    user_id    integer  not null
+        references users
+            on delete cascade,
+    show_id    text     not null
+        references shows
+            on delete cascade,
+    created_at datetime default current_timestamp not null,
+    primary key (user_id, show_id)
+);
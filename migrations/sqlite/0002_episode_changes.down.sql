@@ -0,0 +1,4 @@
+drop table if exists episode_changes;
+
+alter table episodes
+    drop column updated_at;
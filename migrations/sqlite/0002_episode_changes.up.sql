@@ -0,0 +1,23 @@
+-- SQLite cannot add a column with a non-constant default, so existing rows are backfilled.
+alter table episodes
+    add column updated_at datetime;
+
+update episodes
+set updated_at = created_at;
+
+create table if not exists episode_changes
+(
+    id           integer  not null
+        primary key autoincrement,
+    episode_id   text     not null
+        references episodes
+            on delete cascade,
+    old_air_date datetime,
+    new_air_date datetime,
+    old_name     text,
+    new_name     text,
+    changed_at   datetime default current_timestamp not null
+);
+
+create index if not exists idx_episode_changes_episode_id
+    on episode_changes (episode_id);
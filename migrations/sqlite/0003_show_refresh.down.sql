@@ -0,0 +1,2 @@
+alter table shows
+    drop column refreshed_at;
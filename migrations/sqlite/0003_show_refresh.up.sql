@@ -0,0 +1,2 @@
+alter table shows
+    add column refreshed_at datetime;
@@ -0,0 +1,5 @@
+alter table episodes
+    drop column air_stamp;
+
+alter table users
+    drop column timezone;
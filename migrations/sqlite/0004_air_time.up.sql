@@ -0,0 +1,5 @@
+alter table users
+    add column timezone text default '' not null;
+
+alter table episodes
+    add column air_stamp datetime;
@@ -0,0 +1,29 @@
+create table notifications_old
+(
+    id          integer  not null
+        primary key autoincrement,
+    user_id     integer  not null
+        references users
+            on delete cascade,
+    episode_id  text     not null
+        references episodes
+            on delete cascade,
+    notified_at datetime default current_timestamp not null,
+    created_at  datetime default current_timestamp not null,
+    unique (user_id, episode_id)
+);
+
+insert into notifications_old (id, user_id, episode_id, notified_at, created_at)
+select min(id), user_id, episode_id, min(notified_at), min(created_at)
+from notifications
+group by user_id, episode_id;
+
+drop table notifications;
+
+alter table notifications_old
+    rename to notifications;
+
+alter table user_shows
+    drop column muted;
+
+drop table if exists user_settings;
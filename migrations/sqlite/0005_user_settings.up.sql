@@ -0,0 +1,43 @@
+create table if not exists user_settings
+(
+    user_id     integer  not null
+        primary key
+        references users
+            on delete cascade,
+    lead_times  text     default ''    not null,
+    quiet_hours boolean  default false not null,
+    quiet_start integer  default 0     not null,
+    quiet_end   integer  default 0     not null,
+    updated_at  datetime default current_timestamp not null
+);
+
+alter table user_shows
+    add column muted boolean default false not null;
+
+-- A user can now get several reminders per episode, one per kind. SQLite cannot drop
+-- the old unique constraint, so the table is rebuilt; existing rows become the
+-- single reminder everyone used to get.
+create table notifications_new
+(
+    id          integer  not null
+        primary key autoincrement,
+    user_id     integer  not null
+        references users
+            on delete cascade,
+    episode_id  text     not null
+        references episodes
+            on delete cascade,
+    kind        text     default 'default' not null,
+    notified_at datetime default current_timestamp not null,
+    created_at  datetime default current_timestamp not null,
+    unique (user_id, episode_id, kind)
+);
+
+insert into notifications_new (id, user_id, episode_id, notified_at, created_at)
+select id, user_id, episode_id, notified_at, created_at
+from notifications;
+
+drop table notifications;
+
+alter table notifications_new
+    rename to notifications;
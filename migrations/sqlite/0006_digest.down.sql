@@ -0,0 +1,11 @@
+alter table user_settings
+    drop column last_digest_at;
+
+alter table user_settings
+    drop column digest_weekday;
+
+alter table user_settings
+    drop column digest_hour;
+
+alter table user_settings
+    drop column digest;
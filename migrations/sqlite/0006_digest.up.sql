@@ -0,0 +1,11 @@
+alter table user_settings
+    add column digest text default '' not null;
+
+alter table user_settings
+    add column digest_hour integer default 8 not null;
+
+alter table user_settings
+    add column digest_weekday integer default 1 not null;
+
+alter table user_settings
+    add column last_digest_at datetime;
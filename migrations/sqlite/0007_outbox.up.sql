@@ -0,0 +1,17 @@
+create table if not exists outbox_messages
+(
+    id              integer  not null
+        primary key autoincrement,
+    chat_id         integer  not null,
+    text            text     not null,
+    reply_markup    text     default ''        not null,
+    status          text     default 'pending' not null,
+    attempts        integer  default 0         not null,
+    next_attempt_at datetime default current_timestamp not null,
+    last_error      text     default ''        not null,
+    created_at      datetime default current_timestamp not null,
+    sent_at         datetime
+);
+
+create index if not exists idx_outbox_messages_due
+    on outbox_messages (status, next_attempt_at);
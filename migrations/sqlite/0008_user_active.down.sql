@@ -0,0 +1,5 @@
+alter table users
+    drop column deactivated_at;
+
+alter table users
+    drop column active;
@@ -0,0 +1,5 @@
+alter table users
+    add column active boolean default true not null;
+
+alter table users
+    add column deactivated_at datetime;
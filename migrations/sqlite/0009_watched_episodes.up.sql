@@ -0,0 +1,11 @@
+create table if not exists watched_episodes
+(
+    user_id    integer  not null
+        references users
+            on delete cascade,
+    episode_id text     not null
+        references episodes
+            on delete cascade,
+    watched_at datetime default current_timestamp not null,
+    primary key (user_id, episode_id)
+);
@@ -0,0 +1,4 @@
+drop index if exists idx_users_calendar_token;
+
+alter table users
+    drop column calendar_token;
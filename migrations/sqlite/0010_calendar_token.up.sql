@@ -0,0 +1,5 @@
+alter table users
+    add column calendar_token text;
+
+create unique index if not exists idx_users_calendar_token
+    on users (calendar_token);
@@ -0,0 +1,2 @@
+alter table users
+    add column notify_chat_id integer;
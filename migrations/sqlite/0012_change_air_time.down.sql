@@ -0,0 +1,2 @@
+alter table episode_changes
+    drop column old_has_air_time;
@@ -0,0 +1,2 @@
+alter table episode_changes
+    add column old_has_air_time boolean default false not null;